@@ -10,7 +10,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"strings"
+	"sync"
 	"time"
 
 	"go.bug.st/serial"
@@ -21,53 +24,148 @@ import (
 var ErrDSRNotReady = errors.New("asrl: DSR not ready")
 
 // Device models a serial device and implements the ivi.Transport interface.
+// A Device is safe for concurrent use by multiple goroutines. Each Command,
+// Query, and other transaction runs to completion before the next starts, and
+// a sequence of transactions can be made exclusive using Lock.
 type Device struct {
-	endMark       byte
-	hwHandshaking bool
+	// mu guards the settings below, which can be changed while another
+	// goroutine is using the Device.
+	mu            sync.RWMutex
+	writeTerm     string
+	readTerm      string
+	stripTerm     bool
+	termChar      bool
+	flowControl   FlowControl
+	errorChecking bool
 	delayTime     time.Duration
 	readTimeout   time.Duration
+	clearBreak    time.Duration
+	clearString   string
+	retry         RetryPolicy
+	reconnect     bool
+	onReconnect   func(ReconnectEvent)
 	port          serial.Port
+	mode          *serial.Mode
+	link          *reconnectPort
+	xon           *xonxoffPort
+	in            *deadlineReader
 	reader        *bufio.Reader
+	capture       io.Writer
+	logger        *slog.Logger
+	resource      *VisaResource
+	// sem is held by the holder of the exclusive lock from Lock until Unlock,
+	// and otherwise for the duration of each transaction. tx is held for the
+	// duration of every transaction, including those run with the lock
+	// context.
+	sem    chan struct{}
+	tx     chan struct{}
+	holder *lockToken
+	// stale is set when a read fails part way through a response, so that
+	// unread input is discarded before the next transaction. unanswered is
+	// also set if the rest of the response may still be on its way, so that
+	// it is read and discarded first. Both are guarded by tx.
+	stale      bool
+	unanswered bool
 }
 
-// EndMark returns the end-of-message byte used by Command and Query.
-func (d *Device) EndMark() byte { return d.endMark }
+// Resource returns the VISA resource used to open the Device, or nil if the
+// Device was created from an already-open port.
+func (d *Device) Resource() *VisaResource { return d.resource }
+
+// EndMark returns the last byte of the write termination appended by Command,
+// or zero if the write termination is empty.
+func (d *Device) EndMark() byte {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.writeTerm == "" {
+		return 0
+	}
+	return d.writeTerm[len(d.writeTerm)-1]
+}
 
-// SetEndMark sets the end-of-message byte used by Command and Query.
-func (d *Device) SetEndMark(b byte) { d.endMark = b }
+// SetEndMark sets both the write termination appended by Command and the read
+// termination used by Query to the given end-of-message byte.
+func (d *Device) SetEndMark(b byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.writeTerm = string(b)
+	d.readTerm = string(b)
+}
 
-// HWHandshaking returns whether hardware handshaking (DSR polling) is enabled.
-func (d *Device) HWHandshaking() bool { return d.hwHandshaking }
+// HWHandshaking returns whether hardware handshaking (DSR polling) is enabled,
+// which is the case when the flow control method is FlowDTRDSR.
+func (d *Device) HWHandshaking() bool { return d.FlowControl() == FlowDTRDSR }
 
-// SetHWHandshaking enables or disables hardware handshaking (DSR polling).
-func (d *Device) SetHWHandshaking(enabled bool) { d.hwHandshaking = enabled }
+// SetHWHandshaking enables or disables hardware handshaking (DSR polling). It
+// is equivalent to setting the flow control method to FlowDTRDSR or FlowNone
+// with SetFlowControl, except that an error asserting DTR is only logged.
+func (d *Device) SetHWHandshaking(enabled bool) {
+	if err := d.SetFlowControl(hwHandshakingFlowControl(d.FlowControl(), enabled)); err != nil {
+		d.logger.Debug("setting hardware handshaking failed", "err", err)
+	}
+}
 
 // DelayTime returns the delay between serial operations.
-func (d *Device) DelayTime() time.Duration { return d.delayTime }
+func (d *Device) DelayTime() time.Duration {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.delayTime
+}
 
 // SetDelayTime sets the delay between serial operations.
-func (d *Device) SetDelayTime(t time.Duration) { d.delayTime = t }
+func (d *Device) SetDelayTime(t time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.delayTime = t
+}
 
-// ReadTimeout returns the read timeout on the serial port.
-func (d *Device) ReadTimeout() time.Duration { return d.readTimeout }
+// ReadTimeout returns how long reads wait for data to arrive.
+func (d *Device) ReadTimeout() time.Duration {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.readTimeout
+}
 
-// SetReadTimeout sets the read timeout on the serial port.
-func (d *Device) SetReadTimeout(t time.Duration) { d.readTimeout = t }
+// SetReadTimeout sets how long reads wait for data to arrive, which takes
+// effect from the next read.
+func (d *Device) SetReadTimeout(t time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.logger.Debug("read timeout changed", "old", d.readTimeout, "new", t)
+	d.readTimeout = t
+}
 
 // DeviceOption is a functional option for configuring a Device.
 type DeviceOption func(*Device)
 
-// WithEndMark sets the end-of-message byte used by Command and Query.
+// WithEndMark sets both the write termination appended by Command and the read
+// termination used by Query to the given end-of-message byte.
 func WithEndMark(b byte) DeviceOption {
 	return func(d *Device) {
-		d.endMark = b
+		d.writeTerm = string(b)
+		d.readTerm = string(b)
 	}
 }
 
-// WithHWHandshaking enables or disables hardware handshaking (DSR polling).
+// WithHWHandshaking enables or disables hardware handshaking (DSR polling). It
+// is equivalent to WithFlowControl(FlowDTRDSR) or WithFlowControl(FlowNone).
 func WithHWHandshaking(enabled bool) DeviceOption {
 	return func(d *Device) {
-		d.hwHandshaking = enabled
+		d.flowControl = hwHandshakingFlowControl(d.flowControl, enabled)
+	}
+}
+
+// hwHandshakingFlowControl returns the flow control method resulting from
+// enabling or disabling hardware handshaking. Disabling hardware handshaking
+// leaves other flow control methods unchanged.
+func hwHandshakingFlowControl(current FlowControl, enabled bool) FlowControl {
+	switch {
+	case enabled:
+		return FlowDTRDSR
+	case current == FlowDTRDSR:
+		return FlowNone
+	default:
+		return current
 	}
 }
 
@@ -78,7 +176,12 @@ func WithDelayTime(t time.Duration) DeviceOption {
 	}
 }
 
-// WithReadTimeout sets the read timeout on the serial port.
+// defaultReadTimeout is how long reads wait for data to arrive unless
+// overridden using WithReadTimeout.
+const defaultReadTimeout = 5 * time.Second
+
+// WithReadTimeout sets how long reads wait for data to arrive. The default is
+// five seconds.
 func WithReadTimeout(t time.Duration) DeviceOption {
 	return func(d *Device) {
 		d.readTimeout = t
@@ -99,47 +202,239 @@ func NewDevice(ctx context.Context, address string, opts ...DeviceOption) (*Devi
 		return nil, err
 	}
 
-	mode := &serial.Mode{
-		BaudRate: v.baud,
-		Parity:   v.parity,
-		DataBits: v.dataBits,
-		StopBits: v.stopBits,
+	start := time.Now()
+	port, err := openPort(v.address, v.mode())
+	if err != nil {
+		return nil, &OpError{Op: "open", Resource: address, Elapsed: time.Since(start), Err: err}
 	}
-	port, err := serial.Open(v.address, mode)
+
+	d, err := newDevice(port, v, opts...)
 	if err != nil {
-		return nil, err
+		_ = port.Close()
+		return nil, &OpError{Op: "open", Resource: address, Elapsed: time.Since(start), Err: err}
 	}
+	return d, nil
+}
 
+// NewDeviceFromPort creates a Device that communicates over an already-open
+// serial port, such as a pseudo-terminal, a USB bridge opened by the caller, or
+// an in-memory fake used in tests. The same DeviceOption values accepted by
+// NewDevice can be provided. The Device takes ownership of the port, so
+// closing the Device closes the port. If an error is returned, the port is left
+// open for the caller to close.
+func NewDeviceFromPort(port serial.Port, opts ...DeviceOption) (*Device, error) {
+	if port == nil {
+		return nil, errors.New("asrl: nil serial port")
+	}
+	return newDevice(port, nil, opts...)
+}
+
+func newDevice(port serial.Port, v *VisaResource, opts ...DeviceOption) (*Device, error) {
 	d := &Device{
-		port:          port,
-		reader:        bufio.NewReader(port),
-		hwHandshaking: false,
-		endMark:       '\n',
-		delayTime:     70 * time.Millisecond,
-		readTimeout:   5 * time.Second,
+		resource:    v,
+		logger:      slog.New(slog.DiscardHandler),
+		flowControl: FlowNone,
+		writeTerm:   "\n",
+		readTerm:    "\n",
+		termChar:    true,
+		delayTime:   70 * time.Millisecond,
+		readTimeout: defaultReadTimeout,
+		sem:         make(chan struct{}, 1),
+		tx:          make(chan struct{}, 1),
+	}
+	if v != nil {
+		d.mode = v.mode()
 	}
 	for _, opt := range opts {
 		opt(d)
 	}
-	if err := port.SetReadTimeout(d.readTimeout); err != nil {
-		_ = port.Close()
+	if d.capture != nil {
+		d.xon = &xonxoffPort{Port: &capturePort{Port: port, w: d.capture}}
+	} else {
+		d.xon = &xonxoffPort{Port: port}
+	}
+	if d.reconnect && v != nil {
+		d.enableReconnect(port, v.address)
+	}
+	d.port = d.xon
+	d.in = &deadlineReader{port: d.port, ctx: context.Background()}
+	d.reader = bufio.NewReader(d.in)
+	if v != nil {
+		d.logger = d.logger.With("resource", v.String())
+	}
+	if err := port.SetReadTimeout(pollInterval); err != nil {
 		return nil, fmt.Errorf("setting read timeout: %w", err)
 	}
+	if err := d.applyFlowControl(); err != nil {
+		return nil, err
+	}
+	d.logger.Debug("device opened",
+		"readTimeout", d.readTimeout,
+		"delayTime", d.delayTime,
+		"flowControl", d.flowControl,
+	)
 	return d, nil
 }
 
-// Close closes the underlying serial port.
+// NewDeviceFromReadWriteCloser creates a Device that communicates over a
+// generic transport instead of a serial port. Since a generic transport has no
+// modem control lines, the modem status bits always report ready. The
+// transport is read by a goroutine, so that reads honor the read timeout and
+// context even though the transport has no timeout of its own; the goroutine
+// exits when a read from the transport fails, such as after Close.
+func NewDeviceFromReadWriteCloser(
+	rwc io.ReadWriteCloser,
+	opts ...DeviceOption,
+) (*Device, error) {
+	if rwc == nil {
+		return nil, errors.New("asrl: nil transport")
+	}
+	return NewDeviceFromPort(newRWCPort(rwc), opts...)
+}
+
+// rwcPort adapts an io.ReadWriteCloser to the serial.Port interface. Reads
+// from the transport are made by a single goroutine, started by the first
+// Read, so that Read can return when the read timeout passes. Other port
+// configuration calls are accepted and ignored.
+type rwcPort struct {
+	io.ReadWriteCloser
+	start  sync.Once
+	chunks chan rwcChunk
+	done   chan struct{}
+	close  sync.Once
+
+	mu      sync.Mutex
+	timeout time.Duration
+	rest    []byte
+	err     error
+}
+
+// rwcChunk is the result of a read from the transport.
+type rwcChunk struct {
+	data []byte
+	err  error
+}
+
+func newRWCPort(rwc io.ReadWriteCloser) *rwcPort {
+	return &rwcPort{
+		ReadWriteCloser: rwc,
+		chunks:          make(chan rwcChunk),
+		done:            make(chan struct{}),
+		timeout:         serial.NoTimeout,
+	}
+}
+
+// readLoop reads from the transport until a read fails or the port is closed,
+// passing each result to Read.
+func (p *rwcPort) readLoop() {
+	for {
+		buf := make([]byte, 4096)
+		n, err := p.ReadWriteCloser.Read(buf)
+		if n == 0 && err == nil {
+			continue
+		}
+		select {
+		case p.chunks <- rwcChunk{data: buf[:n], err: err}:
+		case <-p.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Read reads data received from the transport, waiting up to the read timeout
+// for it to arrive. Like a serial port, Read returns zero bytes and a nil
+// error if the timeout passes first.
+func (p *rwcPort) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.rest) == 0 && p.err == nil {
+		p.start.Do(func() { go p.readLoop() })
+		var timeout <-chan time.Time
+		if p.timeout >= 0 {
+			t := time.NewTimer(p.timeout)
+			defer t.Stop()
+			timeout = t.C
+		}
+		select {
+		case c := <-p.chunks:
+			p.rest, p.err = c.data, c.err
+		case <-timeout:
+			return 0, nil
+		case <-p.done:
+			return 0, io.ErrClosedPipe
+		}
+	}
+	n := copy(b, p.rest)
+	p.rest = p.rest[n:]
+	if len(p.rest) == 0 && p.err != nil {
+		return n, p.err
+	}
+	return n, nil
+}
+
+// ResetInputBuffer discards data read from the transport but not yet returned
+// by Read.
+func (p *rwcPort) ResetInputBuffer() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rest = nil
+	return nil
+}
+
+// SetReadTimeout sets how long Read waits for data, or disables the timeout
+// when given serial.NoTimeout.
+func (p *rwcPort) SetReadTimeout(t time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.timeout = t
+	return nil
+}
+
+// Close closes the transport and stops the goroutine reading from it.
+func (p *rwcPort) Close() error {
+	p.close.Do(func() { close(p.done) })
+	return p.ReadWriteCloser.Close()
+}
+
+func (p *rwcPort) SetMode(_ *serial.Mode) error { return nil }
+func (p *rwcPort) Drain() error                 { return nil }
+func (p *rwcPort) ResetOutputBuffer() error     { return nil }
+func (p *rwcPort) SetDTR(_ bool) error          { return nil }
+func (p *rwcPort) SetRTS(_ bool) error          { return nil }
+func (p *rwcPort) Break(_ time.Duration) error  { return nil }
+func (p *rwcPort) GetModemStatusBits() (*serial.ModemStatusBits, error) {
+	return &serial.ModemStatusBits{CTS: true, DSR: true, DCD: true}, nil
+}
+
+// Close closes the underlying serial port. Close doesn't wait for other
+// transactions to finish, so a concurrent read fails once the port is closed.
 func (d *Device) Close() error {
-	time.Sleep(d.delayTime)
-	return d.port.Close()
+	time.Sleep(d.DelayTime())
+	err := d.port.Close()
+	d.logger.Debug("device closed", "err", err)
+	return err
 }
 
-// Read reads from the serial port into the given byte slice.
+// Read reads from the serial port into the given byte slice, waiting up to
+// ReadTimeout for data to arrive. If no data arrives in time, Read returns zero
+// bytes and a nil error, as a serial port's Read does. Read bypasses the
+// Device's buffered reader and locking, so it shouldn't be mixed with other
+// methods used concurrently; use ReadBinary instead.
 func (d *Device) Read(p []byte) (n int, err error) {
-	return d.port.Read(p)
+	r := &deadlineReader{port: d.port, ctx: context.Background(), timeout: d.ReadTimeout()}
+	n, err = r.Read(p)
+	if errors.Is(err, ErrTimeout) {
+		err = nil
+	}
+	return n, err
 }
 
-// Write writes the given data to the serial port.
+// Write writes the given data to the serial port. Write bypasses the Device's
+// locking, so it shouldn't be mixed with other methods used concurrently; use
+// WriteBinary instead.
 func (d *Device) Write(p []byte) (n int, err error) {
 	return d.port.Write(p)
 }
@@ -151,35 +446,24 @@ func (d *Device) WriteString(s string) (n int, err error) {
 }
 
 // ReadBinary reads binary data from the serial port without terminator
-// interpretation. If the context is canceled before the read completes,
-// ReadBinary sets a short timeout to unblock the read, waits for the goroutine
-// to finish, and returns the context error.
+// interpretation, waiting up to ReadTimeout for data to arrive. If no data
+// arrives in time, the error returned wraps a *TimeoutError and ErrTimeout. If
+// the context is canceled before data arrives, the error returned wraps the
+// context error.
 func (d *Device) ReadBinary(ctx context.Context, p []byte) (int, error) {
-	if err := ctx.Err(); err != nil {
+	release, err := d.acquire(ctx)
+	if err != nil {
 		return 0, err
 	}
-
-	type result struct {
-		n   int
-		err error
-	}
-	ch := make(chan result, 1)
-	go func() {
-		n, err := d.port.Read(p)
-		ch <- result{n, err}
-	}()
-
-	select {
-	case <-ctx.Done():
-		// Set a short read timeout to unblock the goroutine stuck on Read,
-		// then wait for it to finish so we don't leak it.
-		_ = d.port.SetReadTimeout(1 * time.Millisecond)
-		<-ch
-		_ = d.port.SetReadTimeout(d.readTimeout)
-		return 0, ctx.Err()
-	case r := <-ch:
-		return r.n, r.err
-	}
+	defer release()
+
+	start := time.Now()
+	d.beginRead(ctx, d.ReadTimeout())
+	defer d.endRead()
+	n, err := d.reader.Read(p)
+	err = d.timeoutError(err, nil)
+	d.logger.Log(ctx, LevelTrace, "binary read", "n", n, "err", err)
+	return n, d.opError("read", "", n, start, err)
 }
 
 // WriteBinary writes binary data to the serial port without adding a
@@ -187,70 +471,152 @@ func (d *Device) ReadBinary(ctx context.Context, p []byte) (int, error) {
 // WriteBinary returns the context error. Serial writes are typically
 // non-blocking, so no goroutine-based cancellation is needed.
 func (d *Device) WriteBinary(ctx context.Context, p []byte) (int, error) {
+	release, err := d.acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	start := time.Now()
+	n, err := d.writeBinary(ctx, p)
+	return n, d.opError("write", "", n, start, err)
+}
+
+// writeBinary writes data to the serial port. The caller must hold the lock.
+func (d *Device) writeBinary(ctx context.Context, p []byte) (int, error) {
 	if err := ctx.Err(); err != nil {
 		return 0, err
 	}
 
-	return d.port.Write(p)
+	n, err := d.port.Write(p)
+	d.logger.Log(ctx, LevelTrace, "binary write", "n", n, "err", err)
+	return n, err
 }
 
 // Command sends a SCPI/ASCII command to the serial port. The command can be
 // optionally formatted according to a format specifier. An endmark character,
-// such as newline, is automatically added to the end of the string.
+// such as newline, is automatically added to the end of the string. If error
+// checking is enabled, the instrument's error queue is then drained, and any
+// errors are returned as *SCPIError values. If the retry policy's Commands
+// field is set, a failed command is retried as described by WithRetry.
 func (d *Device) Command(ctx context.Context, cmd string, a ...any) error {
-	if err := ctx.Err(); err != nil {
+	release, err := d.acquire(ctx)
+	if err != nil {
 		return err
 	}
-	if d.hwHandshaking {
-		if err := d.napIfDataSetNotReady(ctx); err != nil {
-			return err
-		}
-	}
+	defer release()
 	if len(a) > 0 {
 		cmd = fmt.Sprintf(cmd, a...)
 	}
-	cmd = strings.TrimSpace(cmd) + string(d.endMark)
-	if _, err := d.WriteBinary(ctx, []byte(cmd)); err != nil {
+	policy := d.retryPolicy(ctx)
+	if !policy.Commands {
+		policy.MaxAttempts = 1
+	}
+	return d.withRetry(ctx, policy, func() error {
+		if err := d.command(ctx, cmd); err != nil {
+			return err
+		}
+		if d.ErrorChecking() {
+			return d.drainErrors(ctx, strings.TrimSpace(cmd))
+		}
+		return nil
+	})
+}
+
+// command sends the command followed by the write termination, without
+// checking the error queue. The caller must hold the lock.
+func (d *Device) command(ctx context.Context, cmd string) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
+	start := time.Now()
+	if err := d.waitForFlowControl(ctx); err != nil {
+		return d.opError("handshake", cmd, 0, start, err)
+	}
+	cmd = strings.TrimSpace(cmd)
+	msg := []byte(cmd + d.WriteTermination())
+	if n, err := d.writeBinary(ctx, msg); err != nil {
+		d.logger.DebugContext(ctx, "command failed",
+			"cmd", cmd, "elapsed", time.Since(start), "err", err)
+		return d.opError("write", cmd, n, start, err)
+	}
+	d.logger.DebugContext(ctx, "command", "cmd", cmd, "elapsed", time.Since(start))
 
-	return sleepContext(ctx, d.delayTime)
+	return d.opError("write", cmd, len(msg), start, sleepContext(ctx, d.DelayTime()))
 }
 
 // Query writes the given SCPI/ASCII command to the serial port and returns the
-// response string. The device's endmark character (newline by default) is
-// automatically added to the query command. The string returned is not
-// stripped of any whitespace. The context is used for cancellation; if the
-// context is canceled while waiting for a response, Query returns the context
-// error.
+// response string. The device's write termination (newline by default) is
+// automatically added to the query command, and the response is read up to
+// and including the read termination (newline by default). The string returned
+// is not stripped of any whitespace, and the read termination is only removed
+// if StripReadTermination is enabled. The context is used for cancellation; if
+// the context is canceled while waiting for a response, Query returns the
+// context error. If the read timeout elapses while waiting for the response,
+// the error returned wraps a *TimeoutError, which wraps ErrTimeout and holds
+// any partial response received. The command and response are a single
+// transaction, so no other goroutine's transaction can come between them.
+//
+// If Query fails before the whole response is read, such as when the context
+// is canceled, the next transaction first reads and discards input up to the
+// read termination, waiting up to ReadTimeout for the rest of the response to
+// arrive, and then discards any other unread input, so the late response isn't
+// mistaken for the response to a later query. A failed Query is retried
+// according to the retry policy set by WithRetry or ContextWithRetry.
 func (d *Device) Query(ctx context.Context, cmd string) (string, error) {
-	if err := d.Command(ctx, "%s", cmd); err != nil {
+	release, err := d.acquire(ctx)
+	if err != nil {
 		return "", err
 	}
+	defer release()
+	var s string
+	err = d.withRetry(ctx, d.retryPolicy(ctx), func() error {
+		var err error
+		s, err = d.query(ctx, cmd, d.ReadTimeout())
+		if err == nil && strings.TrimSpace(s) == "" {
+			return ErrEmptyResponse
+		}
+		return err
+	})
+	if errors.Is(err, ErrEmptyResponse) {
+		err = nil
+	}
+	return s, err
+}
 
-	type result struct {
-		s   string
-		err error
+// query sends the command and reads the response, waiting up to timeout for
+// each part of the response to arrive. The caller must hold the lock.
+func (d *Device) query(ctx context.Context, cmd string, timeout time.Duration) (string, error) {
+	start := time.Now()
+	if err := d.command(ctx, cmd); err != nil {
+		// The command may have been sent before the write failed or the context
+		// was done, in which case the response is still to come.
+		d.stale = true
+		var opErr *OpError
+		if errors.As(err, &opErr) && opErr.Op == "write" {
+			d.unanswered = true
+		}
+		return "", err
 	}
-	ch := make(chan result, 1)
-	go func() {
-		s, err := d.reader.ReadString(d.endMark)
-		ch <- result{s, err}
-	}()
 
-	select {
-	case <-ctx.Done():
-		// Set a short read timeout to unblock the goroutine stuck on
-		// ReadString, then wait for it to finish so we don't leak it or
-		// race on the bufio.Reader.
-		_ = d.port.SetReadTimeout(1 * time.Millisecond)
-		<-ch
-		_ = d.port.SetReadTimeout(d.readTimeout)
-		d.reader.Reset(d.port)
-		return "", ctx.Err()
-	case r := <-ch:
-		return r.s, r.err
+	d.beginRead(ctx, timeout)
+	defer d.endRead()
+	readStart := time.Now()
+	s, err := d.readResponse()
+	if err != nil {
+		d.stale, d.unanswered = true, true
+	}
+	n := len(s)
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		s, err = "", ctxErr
 	}
+	err = d.opError("read", cmd, n, readStart, err)
+	d.logger.DebugContext(ctx, "query",
+		"cmd", strings.TrimSpace(cmd),
+		"response", s,
+		"elapsed", time.Since(start),
+		"err", err,
+	)
+	return s, err
 }
 
 func isDSR(port serial.Port) (bool, error) {
@@ -265,13 +631,37 @@ func (d *Device) napIfDataSetNotReady(ctx context.Context) error {
 	// If I use 40 ms instead of 50 ms for the delay time, the Keysight E3631A DC
 	// power supply will hang when sending commands/queries. Using 50 ms causes
 	// the power supply to hang sometimes. I'm currently using 70 ms to be safe.
-	timeout := time.NewTimer(d.readTimeout)
+	return d.napUntilReady(ctx, "DSR", isDSR, ErrDSRNotReady)
+}
+
+func (d *Device) napIfNotClearToSend(ctx context.Context) error {
+	return d.napUntilReady(ctx, "CTS", isCTS, ErrCTSNotReady)
+}
+
+// minHandshakePoll is the shortest interval between polls of a modem status
+// signal, used when DelayTime is shorter.
+const minHandshakePoll = time.Millisecond
+
+// napUntilReady polls the given modem status signal every DelayTime, or every
+// minHandshakePoll if that is longer, until it is asserted, returning
+// errNotReady if it isn't asserted within ReadTimeout.
+func (d *Device) napUntilReady(
+	ctx context.Context,
+	signal string,
+	isReady func(serial.Port) (bool, error),
+	errNotReady error,
+) error {
+	start := time.Now()
+	readTimeout, delayTime := d.ReadTimeout(), d.DelayTime()
+	timeout := time.NewTimer(readTimeout)
 	defer timeout.Stop()
-	ticker := time.NewTicker(d.delayTime)
+	ticker := time.NewTicker(max(delayTime, minHandshakePoll))
 	defer ticker.Stop()
 
 	for {
-		ready, err := isDSR(d.port)
+		ready, err := isReady(d.port)
+		d.logger.Log(ctx, LevelTrace, signal+" poll",
+			"ready", ready, "elapsed", time.Since(start), "err", err)
 		if err != nil {
 			return err
 		}
@@ -282,14 +672,16 @@ func (d *Device) napIfDataSetNotReady(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-timeout.C:
-			return fmt.Errorf("%w after %s", ErrDSRNotReady, d.readTimeout)
+			d.logger.DebugContext(ctx, signal+" not ready", "elapsed", time.Since(start))
+			return fmt.Errorf("%w after %s", errNotReady, readTimeout)
 		case <-ticker.C:
 		}
 	}
-	// Sleep a bit longer once the Data Set Ready is true. Without this, the
+	d.logger.DebugContext(ctx, signal+" ready", "elapsed", time.Since(start))
+	// Sleep a bit longer once the signal is asserted. Without this, the
 	// Keysight E3631A DC power supply will sometimes hang when sending
 	// commands/queries.
-	return sleepContext(ctx, d.delayTime)
+	return sleepContext(ctx, delayTime)
 }
 
 // sleepContext pauses for the given duration but returns early with the context
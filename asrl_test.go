@@ -6,10 +6,11 @@
 package asrl
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"errors"
+	"io"
+	"net"
 	"testing"
 	"time"
 
@@ -23,6 +24,7 @@ type mockPort struct {
 	readTimeout time.Duration
 	closed      bool
 	dsrReady    bool
+	ctsReady    bool
 	dsrErr      error
 	readErr     error
 	writeErr    error
@@ -63,17 +65,18 @@ func (m *mockPort) GetModemStatusBits() (*serial.ModemStatusBits, error) {
 	if m.dsrErr != nil {
 		return nil, m.dsrErr
 	}
-	return &serial.ModemStatusBits{DSR: m.dsrReady}, nil
+	return &serial.ModemStatusBits{DSR: m.dsrReady, CTS: m.ctsReady}, nil
 }
 
 func newTestDevice(mp *mockPort) *Device {
-	return &Device{
-		port:        mp,
-		reader:      bufio.NewReader(mp),
-		endMark:     '\n',
-		delayTime:   1 * time.Millisecond,
-		readTimeout: 100 * time.Millisecond,
+	d, err := newDevice(mp, nil,
+		WithDelayTime(1*time.Millisecond),
+		WithReadTimeout(100*time.Millisecond),
+	)
+	if err != nil {
+		panic(err)
 	}
+	return d
 }
 
 func TestRead(t *testing.T) {
@@ -297,7 +300,7 @@ func TestCommandWithHWHandshaking(t *testing.T) {
 	mp := newMockPort("")
 	mp.dsrReady = true
 	d := newTestDevice(mp)
-	d.hwHandshaking = true
+	d.flowControl = FlowDTRDSR
 	ctx := context.Background()
 	if err := d.Command(ctx, "*RST"); err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -312,7 +315,7 @@ func TestCommandHWHandshakingDSRError(t *testing.T) {
 	mp := newMockPort("")
 	mp.dsrErr = errors.New("modem error")
 	d := newTestDevice(mp)
-	d.hwHandshaking = true
+	d.flowControl = FlowDTRDSR
 	ctx := context.Background()
 	err := d.Command(ctx, "*RST")
 	if err == nil {
@@ -325,7 +328,7 @@ func TestCommandHWHandshakingTimeout(t *testing.T) {
 	mp := newMockPort("")
 	mp.dsrReady = false
 	d := newTestDevice(mp)
-	d.hwHandshaking = true
+	d.flowControl = FlowDTRDSR
 	d.readTimeout = 5 * time.Millisecond
 	d.delayTime = 1 * time.Millisecond
 	ctx := context.Background()
@@ -383,8 +386,8 @@ func TestDeviceOptions(t *testing.T) {
 		mp := newMockPort("")
 		d := newTestDevice(mp)
 		WithEndMark('\r')(d)
-		if d.endMark != '\r' {
-			t.Errorf("endMark = %q, want %q", d.endMark, '\r')
+		if d.writeTerm != "\r" || d.readTerm != "\r" {
+			t.Errorf("writeTerm, readTerm = %q, %q, want %q", d.writeTerm, d.readTerm, "\r")
 		}
 	})
 
@@ -393,8 +396,8 @@ func TestDeviceOptions(t *testing.T) {
 		mp := newMockPort("")
 		d := newTestDevice(mp)
 		WithHWHandshaking(true)(d)
-		if !d.hwHandshaking {
-			t.Error("hwHandshaking = false, want true")
+		if d.flowControl != FlowDTRDSR {
+			t.Errorf("flowControl = %v, want %v", d.flowControl, FlowDTRDSR)
 		}
 	})
 
@@ -423,7 +426,7 @@ func TestCommandWithCustomEndMark(t *testing.T) {
 	t.Parallel()
 	mp := newMockPort("")
 	d := newTestDevice(mp)
-	d.endMark = '\r'
+	d.SetEndMark('\r')
 	ctx := context.Background()
 	if err := d.Command(ctx, "*RST"); err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -432,3 +435,115 @@ func TestCommandWithCustomEndMark(t *testing.T) {
 		t.Errorf("written = %q, want %q", got, "*RST\r")
 	}
 }
+
+func TestNewDeviceFromPort(t *testing.T) {
+	t.Parallel()
+	mp := newMockPort("Stanford Research Systems,DS345\r")
+	d, err := NewDeviceFromPort(mp,
+		WithEndMark('\r'),
+		WithDelayTime(1*time.Millisecond),
+		WithReadTimeout(250*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mp.readTimeout != pollInterval {
+		t.Errorf("port readTimeout = %v, want %v", mp.readTimeout, pollInterval)
+	}
+	got, err := d.Query(context.Background(), "*IDN?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "Stanford Research Systems,DS345\r"; got != want {
+		t.Errorf("Query = %q, want %q", got, want)
+	}
+	if written := mp.writeBuf.String(); written != "*IDN?\r" {
+		t.Errorf("written = %q, want %q", written, "*IDN?\r")
+	}
+}
+
+func TestNewDeviceFromPortNil(t *testing.T) {
+	t.Parallel()
+	if _, err := NewDeviceFromPort(nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+// rwcBuffer is a minimal io.ReadWriteCloser used to test generic transports.
+type rwcBuffer struct {
+	r      *bytes.Buffer
+	w      *bytes.Buffer
+	closed bool
+}
+
+func (b *rwcBuffer) Read(p []byte) (int, error)  { return b.r.Read(p) }
+func (b *rwcBuffer) Write(p []byte) (int, error) { return b.w.Write(p) }
+func (b *rwcBuffer) Close() error                { b.closed = true; return nil }
+
+func TestNewDeviceFromReadWriteCloser(t *testing.T) {
+	t.Parallel()
+	rwc := &rwcBuffer{
+		r: bytes.NewBufferString("0,\"No error\"\n"),
+		w: &bytes.Buffer{},
+	}
+	d, err := NewDeviceFromReadWriteCloser(rwc,
+		WithHWHandshaking(true),
+		WithDelayTime(1*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := d.Query(context.Background(), "SYST:ERR?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "0,\"No error\"\n"; got != want {
+		t.Errorf("Query = %q, want %q", got, want)
+	}
+	if written := rwc.w.String(); written != "SYST:ERR?\n" {
+		t.Errorf("written = %q, want %q", written, "SYST:ERR?\n")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rwc.closed {
+		t.Error("transport was not closed")
+	}
+}
+
+func TestNewDeviceFromReadWriteCloserTimeout(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		readTimeout time.Duration
+		ctxTimeout  time.Duration
+		want        error
+	}{
+		"read timeout": {50 * time.Millisecond, time.Second, ErrTimeout},
+		"context":      {time.Second, 50 * time.Millisecond, context.DeadlineExceeded},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			host, inst := net.Pipe()
+			defer inst.Close()
+			go func() { _, _ = io.Copy(io.Discard, inst) }()
+			d, err := NewDeviceFromReadWriteCloser(host,
+				WithDelayTime(0),
+				WithReadTimeout(tc.readTimeout),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer d.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), tc.ctxTimeout)
+			defer cancel()
+			start := time.Now()
+			if _, err := d.Query(ctx, "*IDN?"); !errors.Is(err, tc.want) {
+				t.Errorf("err = %v, want %v", err, tc.want)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("Query returned after %v", elapsed)
+			}
+		})
+	}
+}
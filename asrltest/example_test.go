@@ -0,0 +1,39 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrltest_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gotmc/asrl"
+	"github.com/gotmc/asrl/asrltest"
+)
+
+func Example() {
+	inst := asrltest.NewInstrument()
+	inst.Handle("*IDN?", "Agilent Technologies,E3631A,0,2.1-5.0-1.0")
+	inst.HandleRegexp(`^APPL\? P6V$`, `"+1.700000E+00,+1.300000E+00"`)
+
+	dev, err := asrl.NewDeviceFromPort(inst, asrl.WithDelayTime(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer dev.Close()
+
+	ctx := context.Background()
+	idn, err := dev.Query(ctx, "*IDN?")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(idn)
+	fmt.Println(inst.Commands())
+
+	// Output:
+	// Agilent Technologies,E3631A,0,2.1-5.0-1.0
+	// [*IDN?]
+}
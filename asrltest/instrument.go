@@ -0,0 +1,531 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+// Package asrltest provides a simulated serial instrument for testing code
+// built on the asrl package without hardware.
+//
+// An Instrument implements the serial.Port interface, so it can be passed to
+// asrl.NewDeviceFromPort. Commands written by the host are split on the
+// instrument's input terminator and matched against the registered handlers,
+// either exactly or by regular expression. The response of the first matching
+// handler, followed by the output terminator, is queued for the host to read.
+// Commands that match no handler are recorded but produce no response, which
+// mirrors how serial SCPI instruments silently ignore unknown commands.
+//
+// The Instrument can also simulate the Data Set Ready and Clear To Send modem
+// lines, delayed responses, and injected read and write errors, and it records
+// a transcript of everything that crossed the simulated wire.
+package asrltest
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// ErrClosed is returned by Read and Write after the Instrument has been
+// closed.
+var ErrClosed = errors.New("asrltest: port closed")
+
+// Direction identifies which side of the simulated wire sent a transcript
+// record.
+type Direction int
+
+// Available transcript directions.
+const (
+	// FromHost marks data written by the host to the instrument.
+	FromHost Direction = iota
+	// ToHost marks data sent by the instrument to the host.
+	ToHost
+)
+
+// String returns ">" for FromHost and "<" for ToHost.
+func (d Direction) String() string {
+	if d == FromHost {
+		return ">"
+	}
+	return "<"
+}
+
+// Record is a single transcript entry.
+type Record struct {
+	Time      time.Time
+	Direction Direction
+	Data      string
+}
+
+// ResponseFunc computes the response to a command. For exact handlers, match
+// holds only the command. For regular expression handlers, match holds the
+// full match followed by any submatches, as returned by
+// regexp.Regexp.FindStringSubmatch. Returning an empty string sends no
+// response.
+type ResponseFunc func(match []string) string
+
+type handler struct {
+	cmd string
+	re  *regexp.Regexp
+	fn  ResponseFunc
+}
+
+func (h handler) match(cmd string) []string {
+	if h.re != nil {
+		return h.re.FindStringSubmatch(cmd)
+	}
+	if cmd == h.cmd {
+		return []string{cmd}
+	}
+	return nil
+}
+
+var _ serial.Port = (*Instrument)(nil)
+
+type chunk struct {
+	data    []byte
+	readyAt time.Time
+}
+
+// Instrument is a simulated serial instrument that implements serial.Port. It
+// is safe for concurrent use.
+type Instrument struct {
+	mu            sync.Mutex
+	dispatchMu    sync.Mutex
+	changed       chan struct{}
+	inTerm        string
+	outTerm       string
+	handlers      []handler
+	in            []byte
+	out           []chunk
+	transcript    []Record
+	commands      []string
+	mode          serial.Mode
+	readTimeout   time.Duration
+	responseDelay time.Duration
+	dsr           bool
+	cts           bool
+	dtr           bool
+	rts           bool
+	breaks        int
+	readErr       error
+	writeErr      error
+	closed        bool
+}
+
+// Option is a functional option for configuring an Instrument.
+type Option func(*Instrument)
+
+// WithInputTerminator sets the terminator that separates commands written by
+// the host. The default is "\n".
+func WithInputTerminator(term string) Option {
+	return func(i *Instrument) {
+		i.inTerm = term
+	}
+}
+
+// WithOutputTerminator sets the terminator appended to every response. The
+// default is "\n".
+func WithOutputTerminator(term string) Option {
+	return func(i *Instrument) {
+		i.outTerm = term
+	}
+}
+
+// WithResponseDelay sets how long the instrument waits before a response
+// becomes readable.
+func WithResponseDelay(d time.Duration) Option {
+	return func(i *Instrument) {
+		i.responseDelay = d
+	}
+}
+
+// NewInstrument creates a simulated instrument with DSR and CTS asserted and
+// no registered handlers.
+func NewInstrument(opts ...Option) *Instrument {
+	i := &Instrument{
+		changed:     make(chan struct{}),
+		inTerm:      "\n",
+		outTerm:     "\n",
+		readTimeout: serial.NoTimeout,
+		dsr:         true,
+		cts:         true,
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Handle registers a canned response for a command that exactly matches cmd
+// after surrounding whitespace is trimmed.
+func (i *Instrument) Handle(cmd, response string) {
+	i.HandleFunc(cmd, func([]string) string { return response })
+}
+
+// HandleFunc registers a computed response for a command that exactly matches
+// cmd after surrounding whitespace is trimmed.
+func (i *Instrument) HandleFunc(cmd string, fn ResponseFunc) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.handlers = append(i.handlers, handler{cmd: cmd, fn: fn})
+}
+
+// HandleRegexp registers a canned response for commands matching the regular
+// expression pattern. It panics if the pattern does not compile.
+func (i *Instrument) HandleRegexp(pattern, response string) {
+	i.HandleRegexpFunc(pattern, func([]string) string { return response })
+}
+
+// HandleRegexpFunc registers a computed response for commands matching the
+// regular expression pattern. It panics if the pattern does not compile.
+func (i *Instrument) HandleRegexpFunc(pattern string, fn ResponseFunc) {
+	re := regexp.MustCompile(pattern)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.handlers = append(i.handlers, handler{re: re, fn: fn})
+}
+
+// Send queues unsolicited data for the host to read. No terminator is added.
+func (i *Instrument) Send(data string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.queue([]byte(data))
+}
+
+// SetResponseDelay sets how long the instrument waits before a response
+// becomes readable.
+func (i *Instrument) SetResponseDelay(d time.Duration) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.responseDelay = d
+}
+
+// SetDSR sets the simulated Data Set Ready line.
+func (i *Instrument) SetDSR(ready bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.dsr = ready
+	i.broadcast()
+}
+
+// SetDSRAfter sets the simulated Data Set Ready line once the given delay has
+// elapsed, which is useful for simulating an instrument that is busy for a
+// while after a command.
+func (i *Instrument) SetDSRAfter(ready bool, delay time.Duration) {
+	time.AfterFunc(delay, func() { i.SetDSR(ready) })
+}
+
+// SetCTS sets the simulated Clear To Send line.
+func (i *Instrument) SetCTS(ready bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.cts = ready
+	i.broadcast()
+}
+
+// InjectReadError causes the next Read to return err.
+func (i *Instrument) InjectReadError(err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.readErr = err
+	i.broadcast()
+}
+
+// InjectWriteError causes the next Write to return err.
+func (i *Instrument) InjectWriteError(err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.writeErr = err
+}
+
+// Transcript returns a copy of everything written by the host and sent by the
+// instrument, in order.
+func (i *Instrument) Transcript() []Record {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]Record(nil), i.transcript...)
+}
+
+// Commands returns the commands received from the host, without terminators,
+// in order.
+func (i *Instrument) Commands() []string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]string(nil), i.commands...)
+}
+
+// Mode returns the mode most recently set by the host.
+func (i *Instrument) Mode() serial.Mode {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.mode
+}
+
+// DTR returns the Data Terminal Ready line as last set by the host.
+func (i *Instrument) DTR() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.dtr
+}
+
+// RTS returns the Request To Send line as last set by the host.
+func (i *Instrument) RTS() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rts
+}
+
+// Breaks returns the number of serial breaks sent by the host.
+func (i *Instrument) Breaks() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.breaks
+}
+
+// Closed reports whether the host has closed the port.
+func (i *Instrument) Closed() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.closed
+}
+
+// SetMode records the serial mode requested by the host.
+func (i *Instrument) SetMode(mode *serial.Mode) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if mode != nil {
+		i.mode = *mode
+	}
+	return nil
+}
+
+// Read reads data sent by the instrument. Like a real serial port, Read blocks
+// until at least one byte is available and returns zero bytes and a nil error
+// if the read timeout elapses first.
+func (i *Instrument) Read(p []byte) (int, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var deadline time.Time
+	if i.readTimeout >= 0 {
+		deadline = time.Now().Add(i.readTimeout)
+	}
+	for {
+		if i.closed {
+			return 0, ErrClosed
+		}
+		if err := i.readErr; err != nil {
+			i.readErr = nil
+			return 0, err
+		}
+		now := time.Now()
+		wake := deadline
+		if len(i.out) > 0 {
+			if !i.out[0].readyAt.After(now) {
+				return i.take(p), nil
+			}
+			if wake.IsZero() || i.out[0].readyAt.Before(wake) {
+				wake = i.out[0].readyAt
+			}
+		}
+		if !deadline.IsZero() && !now.Before(deadline) {
+			return 0, nil
+		}
+
+		changed := i.changed
+		i.mu.Unlock()
+		if wake.IsZero() {
+			<-changed
+		} else {
+			t := time.NewTimer(wake.Sub(now))
+			select {
+			case <-changed:
+			case <-t.C:
+			}
+			t.Stop()
+		}
+		i.mu.Lock()
+	}
+}
+
+// take copies ready output into p. The caller must hold i.mu.
+func (i *Instrument) take(p []byte) int {
+	var n int
+	now := time.Now()
+	for len(i.out) > 0 && n < len(p) && !i.out[0].readyAt.After(now) {
+		c := &i.out[0]
+		m := copy(p[n:], c.data)
+		n += m
+		c.data = c.data[m:]
+		if len(c.data) == 0 {
+			i.out = i.out[1:]
+		}
+	}
+	return n
+}
+
+// Write receives data from the host, dispatching each complete command to the
+// registered handlers. Handlers run without the Instrument's lock held, so they
+// may call the Instrument's methods, such as Mode or SetDSR.
+func (i *Instrument) Write(p []byte) (int, error) {
+	i.dispatchMu.Lock()
+	defer i.dispatchMu.Unlock()
+
+	i.mu.Lock()
+	if i.closed {
+		i.mu.Unlock()
+		return 0, ErrClosed
+	}
+	if err := i.writeErr; err != nil {
+		i.writeErr = nil
+		i.mu.Unlock()
+		return 0, err
+	}
+	i.record(FromHost, p)
+	i.in = append(i.in, p...)
+	var cmds []string
+	for i.inTerm != "" {
+		idx := strings.Index(string(i.in), i.inTerm)
+		if idx < 0 {
+			break
+		}
+		cmds = append(cmds, strings.TrimSpace(string(i.in[:idx])))
+		i.in = i.in[idx+len(i.inTerm):]
+	}
+	i.commands = append(i.commands, cmds...)
+	handlers := i.handlers
+	outTerm := i.outTerm
+	i.mu.Unlock()
+
+	for _, cmd := range cmds {
+		if resp := respond(handlers, cmd); resp != "" {
+			i.mu.Lock()
+			i.queue([]byte(resp + outTerm))
+			i.mu.Unlock()
+		}
+	}
+	return len(p), nil
+}
+
+// respond runs the first handler matching cmd and returns its response.
+func respond(handlers []handler, cmd string) string {
+	for _, h := range handlers {
+		if match := h.match(cmd); match != nil {
+			return h.fn(match)
+		}
+	}
+	return ""
+}
+
+// queue makes data readable by the host after the response delay. The caller
+// must hold i.mu.
+func (i *Instrument) queue(data []byte) {
+	i.record(ToHost, data)
+	i.out = append(i.out, chunk{
+		data:    data,
+		readyAt: time.Now().Add(i.responseDelay),
+	})
+	i.broadcast()
+}
+
+// record appends to the transcript. The caller must hold i.mu.
+func (i *Instrument) record(dir Direction, data []byte) {
+	i.transcript = append(i.transcript, Record{
+		Time:      time.Now(),
+		Direction: dir,
+		Data:      string(data),
+	})
+}
+
+// broadcast wakes any blocked Read. The caller must hold i.mu.
+func (i *Instrument) broadcast() {
+	close(i.changed)
+	i.changed = make(chan struct{})
+}
+
+// Drain is a no-op since writes are delivered immediately.
+func (i *Instrument) Drain() error { return nil }
+
+// ResetInputBuffer discards any output the host has received but not yet
+// read. Like a real serial port, it can't discard output that hasn't arrived,
+// so responses delayed by WithResponseDelay or SetResponseDelay are kept until
+// they are ready.
+func (i *Instrument) ResetInputBuffer() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	now := time.Now()
+	kept := i.out[:0]
+	for _, c := range i.out {
+		if c.readyAt.After(now) {
+			kept = append(kept, c)
+		}
+	}
+	i.out = kept
+	return nil
+}
+
+// ResetOutputBuffer discards any partially written command.
+func (i *Instrument) ResetOutputBuffer() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.in = nil
+	return nil
+}
+
+// SetDTR records the Data Terminal Ready line set by the host.
+func (i *Instrument) SetDTR(dtr bool) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.dtr = dtr
+	return nil
+}
+
+// SetRTS records the Request To Send line set by the host.
+func (i *Instrument) SetRTS(rts bool) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rts = rts
+	return nil
+}
+
+// GetModemStatusBits returns the simulated DSR and CTS lines.
+func (i *Instrument) GetModemStatusBits() (*serial.ModemStatusBits, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.closed {
+		return nil, ErrClosed
+	}
+	return &serial.ModemStatusBits{DSR: i.dsr, CTS: i.cts}, nil
+}
+
+// SetReadTimeout sets the read timeout, or disables it when given
+// serial.NoTimeout.
+func (i *Instrument) SetReadTimeout(t time.Duration) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.readTimeout = t
+	i.broadcast()
+	return nil
+}
+
+// Close closes the simulated port and unblocks any pending Read.
+func (i *Instrument) Close() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.closed = true
+	i.broadcast()
+	return nil
+}
+
+// Break records a serial break and discards any partially written command.
+func (i *Instrument) Break(_ time.Duration) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.breaks++
+	i.in = nil
+	return nil
+}
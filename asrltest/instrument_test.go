@@ -0,0 +1,231 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrltest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gotmc/asrl"
+	"github.com/gotmc/asrl/asrltest"
+)
+
+func newDevice(t *testing.T, inst *asrltest.Instrument, opts ...asrl.DeviceOption) *asrl.Device {
+	t.Helper()
+	opts = append([]asrl.DeviceOption{
+		asrl.WithDelayTime(1 * time.Millisecond),
+		asrl.WithReadTimeout(100 * time.Millisecond),
+	}, opts...)
+	d, err := asrl.NewDeviceFromPort(inst, opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return d
+}
+
+func TestHandle(t *testing.T) {
+	t.Parallel()
+	inst := asrltest.NewInstrument()
+	inst.Handle("*IDN?", "Stanford Research Systems,DS345,0,1.0")
+	d := newDevice(t, inst)
+	got, err := d.Query(context.Background(), "*IDN?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "Stanford Research Systems,DS345,0,1.0\n"; got != want {
+		t.Errorf("Query = %q, want %q", got, want)
+	}
+}
+
+func TestHandleRegexpFunc(t *testing.T) {
+	t.Parallel()
+	inst := asrltest.NewInstrument(
+		asrltest.WithInputTerminator("\r"),
+		asrltest.WithOutputTerminator("\r"),
+	)
+	var freq float64
+	inst.HandleRegexpFunc(`^FREQ ([0-9.]+)$`, func(m []string) string {
+		freq, _ = strconv.ParseFloat(m[1], 64)
+		return ""
+	})
+	inst.HandleFunc("FREQ?", func([]string) string {
+		return fmt.Sprintf("%g", freq)
+	})
+	d := newDevice(t, inst, asrl.WithEndMark('\r'))
+	ctx := context.Background()
+	if err := d.Command(ctx, "FREQ %g", 100.5); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := d.Query(ctx, "FREQ?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "100.5\r" {
+		t.Errorf("Query = %q, want %q", got, "100.5\r")
+	}
+	wantCmds := []string{"FREQ 100.5", "FREQ?"}
+	cmds := inst.Commands()
+	if len(cmds) != len(wantCmds) {
+		t.Fatalf("Commands = %q, want %q", cmds, wantCmds)
+	}
+	for i := range cmds {
+		if cmds[i] != wantCmds[i] {
+			t.Errorf("Commands[%d] = %q, want %q", i, cmds[i], wantCmds[i])
+		}
+	}
+}
+
+func TestUnhandledCommandTimesOut(t *testing.T) {
+	t.Parallel()
+	inst := asrltest.NewInstrument()
+	if err := inst.SetReadTimeout(5 * time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := inst.Write([]byte("BOGUS?\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n, err := inst.Read(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Read returned %d bytes, want 0", n)
+	}
+}
+
+func TestResponseDelay(t *testing.T) {
+	t.Parallel()
+	const delay = 20 * time.Millisecond
+	inst := asrltest.NewInstrument(asrltest.WithResponseDelay(delay))
+	inst.Handle("*OPC?", "1")
+	d := newDevice(t, inst)
+	start := time.Now()
+	got, err := d.Query(context.Background(), "*OPC?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "1\n" {
+		t.Errorf("Query = %q, want %q", got, "1\n")
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("Query returned after %v, want at least %v", elapsed, delay)
+	}
+}
+
+func TestResetInputBufferKeepsDelayedOutput(t *testing.T) {
+	t.Parallel()
+	inst := asrltest.NewInstrument()
+	inst.Send("old\n")
+	inst.SetResponseDelay(20 * time.Millisecond)
+	inst.Handle("*OPC?", "1")
+	if _, err := inst.Write([]byte("*OPC?\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Only the output already received is discarded.
+	if err := inst.ResetInputBuffer(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := inst.SetReadTimeout(time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buf := make([]byte, 16)
+	n, err := inst.Read(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := string(buf[:n]); got != "1\n" {
+		t.Errorf("Read = %q, want %q", got, "1\n")
+	}
+}
+
+func TestDSRToggling(t *testing.T) {
+	t.Parallel()
+	inst := asrltest.NewInstrument()
+	inst.SetDSR(false)
+	d := newDevice(t, inst,
+		asrl.WithHWHandshaking(true),
+		asrl.WithReadTimeout(10*time.Millisecond),
+	)
+	ctx := context.Background()
+	if err := d.Command(ctx, "*RST"); !errors.Is(err, asrl.ErrDSRNotReady) {
+		t.Fatalf("err = %v, want %v", err, asrl.ErrDSRNotReady)
+	}
+	inst.SetDSRAfter(true, 2*time.Millisecond)
+	d.SetReadTimeout(time.Second)
+	if err := d.Command(ctx, "*RST"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestInjectedErrors(t *testing.T) {
+	t.Parallel()
+	inst := asrltest.NewInstrument()
+	inst.Handle("*IDN?", "ACME,1")
+	d := newDevice(t, inst)
+	ctx := context.Background()
+
+	errWrite := errors.New("write failed")
+	inst.InjectWriteError(errWrite)
+	if err := d.Command(ctx, "*RST"); !errors.Is(err, errWrite) {
+		t.Fatalf("err = %v, want %v", err, errWrite)
+	}
+
+	errRead := errors.New("read failed")
+	inst.InjectReadError(errRead)
+	if _, err := d.Query(ctx, "*IDN?"); !errors.Is(err, errRead) {
+		t.Fatalf("err = %v, want %v", err, errRead)
+	}
+}
+
+func TestTranscript(t *testing.T) {
+	t.Parallel()
+	inst := asrltest.NewInstrument()
+	inst.Handle("*IDN?", "ACME,1")
+	d := newDevice(t, inst)
+	if _, err := d.Query(context.Background(), "*IDN?"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []asrltest.Record{
+		{Direction: asrltest.FromHost, Data: "*IDN?\n"},
+		{Direction: asrltest.ToHost, Data: "ACME,1\n"},
+	}
+	got := inst.Transcript()
+	if len(got) != len(want) {
+		t.Fatalf("Transcript has %d records, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Direction != want[i].Direction || got[i].Data != want[i].Data {
+			t.Errorf("Transcript[%d] = %s %q, want %s %q",
+				i, got[i].Direction, got[i].Data, want[i].Direction, want[i].Data)
+		}
+		if got[i].Time.IsZero() {
+			t.Errorf("Transcript[%d] has zero time", i)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	t.Parallel()
+	inst := asrltest.NewInstrument()
+	done := make(chan error, 1)
+	go func() {
+		_, err := inst.Read(make([]byte, 1))
+		done <- err
+	}()
+	if err := inst.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := <-done; !errors.Is(err, asrltest.ErrClosed) {
+		t.Fatalf("err = %v, want %v", err, asrltest.ErrClosed)
+	}
+	if !inst.Closed() {
+		t.Error("Closed = false, want true")
+	}
+}
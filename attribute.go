@@ -0,0 +1,413 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// Sentinel errors returned by GetAttribute and SetAttribute.
+var (
+	ErrUnsupportedAttribute  = errors.New("asrl: unsupported attribute")
+	ErrReadOnlyAttribute     = errors.New("asrl: read-only attribute")
+	ErrInvalidAttributeValue = errors.New("asrl: invalid attribute value")
+)
+
+// Attribute identifies a setting of a Device for GetAttribute and
+// SetAttribute, similar to a VISA attribute. The type of each attribute's
+// value is given in its description.
+type Attribute int
+
+// Attributes corresponding to VISA attributes, named after them, such as
+// AttrASRLBaud for VI_ATTR_ASRL_BAUD.
+const (
+	// AttrRsrcName is the VISA resource string used to open the Device, as a
+	// string. It is read only.
+	AttrRsrcName Attribute = iota + 1
+	// AttrRsrcClass is the resource class, INSTR, as a string. It is read only.
+	AttrRsrcClass
+	// AttrIntfType is the interface type, ASRL, as a string. It is read only.
+	AttrIntfType
+	// AttrIntfNum is the board number, as an int. It is read only.
+	AttrIntfNum
+	// AttrIntfInstName is the operating system's name for the serial port,
+	// such as /dev/ttyUSB0 or COM3, as a string. It is read only.
+	AttrIntfInstName
+	// AttrTMOValue is the read timeout, as a time.Duration.
+	AttrTMOValue
+	// AttrTermChar is the read termination character, as a byte. Setting it
+	// sets the read termination to that single byte.
+	AttrTermChar
+	// AttrTermCharEn is whether responses end at the read termination, as a
+	// bool.
+	AttrTermCharEn
+	// AttrASRLBaud is the baud rate, as an int.
+	AttrASRLBaud
+	// AttrASRLDataBits is the number of data bits, 5 to 8, as an int.
+	AttrASRLDataBits
+	// AttrASRLParity is the parity, as a serial.Parity.
+	AttrASRLParity
+	// AttrASRLStopBits is the number of stop bits, as a serial.StopBits.
+	AttrASRLStopBits
+	// AttrASRLFlowCntrl is the flow control method, as a FlowControl.
+	AttrASRLFlowCntrl
+	// AttrASRLBreakLen is the duration of the serial break sent by Clear, as a
+	// time.Duration.
+	AttrASRLBreakLen
+	// AttrASRLCTSState is whether the instrument asserts Clear To Send, as a
+	// bool. It is read only.
+	AttrASRLCTSState
+	// AttrASRLDSRState is whether the instrument asserts Data Set Ready, as a
+	// bool. It is read only.
+	AttrASRLDSRState
+	// AttrASRLDCDState is whether the instrument asserts Data Carrier Detect,
+	// as a bool. It is read only.
+	AttrASRLDCDState
+	// AttrASRLRIState is whether the instrument asserts Ring Indicator, as a
+	// bool. It is read only.
+	AttrASRLRIState
+)
+
+// Attributes for settings of a Device that have no VISA attribute.
+const (
+	// AttrWriteTermination is the write termination, as a string.
+	AttrWriteTermination Attribute = iota + 101
+	// AttrReadTermination is the read termination, as a string.
+	AttrReadTermination
+	// AttrStripReadTermination is whether the read termination is removed
+	// from responses, as a bool.
+	AttrStripReadTermination
+	// AttrDelayTime is the delay between serial operations, as a
+	// time.Duration.
+	AttrDelayTime
+	// AttrErrorChecking is whether the error queue is drained after every
+	// command, as a bool.
+	AttrErrorChecking
+	// AttrClearString is the data sent by Clear, as a string.
+	AttrClearString
+	// AttrRetryPolicy is the retry policy, as a RetryPolicy.
+	AttrRetryPolicy
+)
+
+// attribute describes how to get and set an Attribute. A nil set means the
+// attribute is read only.
+type attribute struct {
+	name string
+	get  func(d *Device) (any, error)
+	set  func(ctx context.Context, d *Device, v any) error
+}
+
+var attributes = map[Attribute]attribute{
+	AttrRsrcName: {
+		name: "VI_ATTR_RSRC_NAME",
+		get:  resourceAttribute((*VisaResource).String),
+	},
+	AttrRsrcClass: {
+		name: "VI_ATTR_RSRC_CLASS",
+		get:  resourceAttribute((*VisaResource).ResourceClass),
+	},
+	AttrIntfType: {
+		name: "VI_ATTR_INTF_TYPE",
+		get:  resourceAttribute((*VisaResource).InterfaceType),
+	},
+	AttrIntfNum: {
+		name: "VI_ATTR_INTF_NUM",
+		get:  resourceAttribute((*VisaResource).BoardIndex),
+	},
+	AttrIntfInstName: {
+		name: "VI_ATTR_INTF_INST_NAME",
+		get:  resourceAttribute((*VisaResource).Address),
+	},
+	AttrTMOValue: {
+		name: "VI_ATTR_TMO_VALUE",
+		get:  func(d *Device) (any, error) { return d.ReadTimeout(), nil },
+		set:  setter((*Device).SetReadTimeout),
+	},
+	AttrTermChar: {
+		name: "VI_ATTR_TERMCHAR",
+		get: func(d *Device) (any, error) {
+			term := d.ReadTermination()
+			if term == "" {
+				return byte(0), nil
+			}
+			return term[len(term)-1], nil
+		},
+		set: setter(func(d *Device, b byte) { d.SetReadTermination(string(b)) }),
+	},
+	AttrTermCharEn: {
+		name: "VI_ATTR_TERMCHAR_EN",
+		get:  func(d *Device) (any, error) { return d.TermCharEnabled(), nil },
+		set:  setter((*Device).SetTermCharEnabled),
+	},
+	AttrASRLBaud: {
+		name: "VI_ATTR_ASRL_BAUD",
+		get:  modeAttribute(func(m *serial.Mode) any { return m.BaudRate }),
+		set:  modeSetter(func(m *serial.Mode, baud int) { m.BaudRate = baud }),
+	},
+	AttrASRLDataBits: {
+		name: "VI_ATTR_ASRL_DATA_BITS",
+		get:  modeAttribute(func(m *serial.Mode) any { return m.DataBits }),
+		set:  modeSetter(func(m *serial.Mode, bits int) { m.DataBits = bits }),
+	},
+	AttrASRLParity: {
+		name: "VI_ATTR_ASRL_PARITY",
+		get:  modeAttribute(func(m *serial.Mode) any { return m.Parity }),
+		set:  modeSetter(func(m *serial.Mode, p serial.Parity) { m.Parity = p }),
+	},
+	AttrASRLStopBits: {
+		name: "VI_ATTR_ASRL_STOP_BITS",
+		get:  modeAttribute(func(m *serial.Mode) any { return m.StopBits }),
+		set:  modeSetter(func(m *serial.Mode, s serial.StopBits) { m.StopBits = s }),
+	},
+	AttrASRLFlowCntrl: {
+		name: "VI_ATTR_ASRL_FLOW_CNTRL",
+		get:  func(d *Device) (any, error) { return d.FlowControl(), nil },
+		set: func(_ context.Context, d *Device, v any) error {
+			f, err := attributeValue[FlowControl](v)
+			if err != nil {
+				return err
+			}
+			return d.SetFlowControl(f)
+		},
+	},
+	AttrASRLBreakLen: {
+		name: "VI_ATTR_ASRL_BREAK_LEN",
+		get:  func(d *Device) (any, error) { return d.ClearBreak(), nil },
+		set:  setter((*Device).SetClearBreak),
+	},
+	AttrASRLCTSState: {
+		name: "VI_ATTR_ASRL_CTS_STATE",
+		get:  modemAttribute(func(b *serial.ModemStatusBits) bool { return b.CTS }),
+	},
+	AttrASRLDSRState: {
+		name: "VI_ATTR_ASRL_DSR_STATE",
+		get:  modemAttribute(func(b *serial.ModemStatusBits) bool { return b.DSR }),
+	},
+	AttrASRLDCDState: {
+		name: "VI_ATTR_ASRL_DCD_STATE",
+		get:  modemAttribute(func(b *serial.ModemStatusBits) bool { return b.DCD }),
+	},
+	AttrASRLRIState: {
+		name: "VI_ATTR_ASRL_RI_STATE",
+		get:  modemAttribute(func(b *serial.ModemStatusBits) bool { return b.RI }),
+	},
+	AttrWriteTermination: {
+		name: "WriteTermination",
+		get:  func(d *Device) (any, error) { return d.WriteTermination(), nil },
+		set:  setter((*Device).SetWriteTermination),
+	},
+	AttrReadTermination: {
+		name: "ReadTermination",
+		get:  func(d *Device) (any, error) { return d.ReadTermination(), nil },
+		set:  setter((*Device).SetReadTermination),
+	},
+	AttrStripReadTermination: {
+		name: "StripReadTermination",
+		get:  func(d *Device) (any, error) { return d.StripReadTermination(), nil },
+		set:  setter((*Device).SetStripReadTermination),
+	},
+	AttrDelayTime: {
+		name: "DelayTime",
+		get:  func(d *Device) (any, error) { return d.DelayTime(), nil },
+		set:  setter((*Device).SetDelayTime),
+	},
+	AttrErrorChecking: {
+		name: "ErrorChecking",
+		get:  func(d *Device) (any, error) { return d.ErrorChecking(), nil },
+		set:  setter((*Device).SetErrorChecking),
+	},
+	AttrClearString: {
+		name: "ClearString",
+		get:  func(d *Device) (any, error) { return d.ClearString(), nil },
+		set:  setter((*Device).SetClearString),
+	},
+	AttrRetryPolicy: {
+		name: "RetryPolicy",
+		get:  func(d *Device) (any, error) { return d.RetryPolicy(), nil },
+		set:  setter((*Device).SetRetryPolicy),
+	},
+}
+
+// String returns the name of the attribute, which for VISA attributes is the
+// VISA name, such as VI_ATTR_ASRL_BAUD.
+func (a Attribute) String() string {
+	if attr, ok := attributes[a]; ok {
+		return attr.name
+	}
+	return fmt.Sprintf("Attribute(%d)", int(a))
+}
+
+// GetAttribute returns the value of the given attribute, whose type is given
+// in the attribute's description. The attributes taken from the VISA resource
+// string, such as AttrRsrcName, and the serial settings, such as AttrASRLBaud,
+// are unsupported for a Device that wasn't opened using a VISA resource string.
+// The modem status attributes, such as AttrASRLCTSState, are read from the
+// port.
+func (d *Device) GetAttribute(attr Attribute) (any, error) {
+	a, ok := attributes[attr]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAttribute, attr)
+	}
+	v, err := a.get(d)
+	if err != nil {
+		return nil, fmt.Errorf("getting %s: %w", attr, err)
+	}
+	return v, nil
+}
+
+// SetAttribute sets the given attribute to value, which must have the type
+// given in the attribute's description. Setting the baud rate, data bits,
+// parity, or stop bits changes the port's settings without reopening it,
+// waiting for any transaction in progress to finish, or until the context is
+// done. Since the other serial settings of a Device created from an
+// already-open port are unknown, its serial settings can't be set and return
+// ErrUnsupportedAttribute. The Device's Resource isn't changed.
+func (d *Device) SetAttribute(ctx context.Context, attr Attribute, value any) error {
+	a, ok := attributes[attr]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedAttribute, attr)
+	}
+	if a.set == nil {
+		return fmt.Errorf("%w: %s", ErrReadOnlyAttribute, attr)
+	}
+	if err := a.set(ctx, d, value); err != nil {
+		return fmt.Errorf("setting %s: %w", attr, err)
+	}
+	return nil
+}
+
+// attributeValue returns v as the type of an attribute's value.
+func attributeValue[T any](v any) (T, error) {
+	t, ok := v.(T)
+	if !ok {
+		return t, fmt.Errorf("%w: got %T, want %T", ErrInvalidAttributeValue, v, t)
+	}
+	return t, nil
+}
+
+// setter returns an attribute set function that calls the given setter.
+func setter[T any](set func(*Device, T)) func(context.Context, *Device, any) error {
+	return func(_ context.Context, d *Device, v any) error {
+		t, err := attributeValue[T](v)
+		if err != nil {
+			return err
+		}
+		set(d, t)
+		return nil
+	}
+}
+
+// resourceAttribute returns an attribute get function for a field of the
+// Device's VISA resource.
+func resourceAttribute[T any](get func(*VisaResource) T) func(*Device) (any, error) {
+	return func(d *Device) (any, error) {
+		v := d.Resource()
+		if v == nil {
+			return nil, fmt.Errorf("%w: device has no VISA resource", ErrUnsupportedAttribute)
+		}
+		return get(v), nil
+	}
+}
+
+// modemAttribute returns an attribute get function for a modem status line.
+func modemAttribute(get func(*serial.ModemStatusBits) bool) func(*Device) (any, error) {
+	return func(d *Device) (any, error) {
+		bits, err := d.port.GetModemStatusBits()
+		if err != nil {
+			return nil, fmt.Errorf("getting modem status bits: %w", err)
+		}
+		return get(bits), nil
+	}
+}
+
+// modeAttribute returns an attribute get function for a serial setting.
+func modeAttribute(get func(*serial.Mode) any) func(*Device) (any, error) {
+	return func(d *Device) (any, error) {
+		mode := d.serialMode()
+		if mode == nil {
+			return nil, fmt.Errorf("%w: serial settings of port unknown", ErrUnsupportedAttribute)
+		}
+		return get(mode), nil
+	}
+}
+
+// modeSetter returns an attribute set function that changes a serial setting.
+func modeSetter[T any](set func(*serial.Mode, T)) func(context.Context, *Device, any) error {
+	return func(ctx context.Context, d *Device, v any) error {
+		t, err := attributeValue[T](v)
+		if err != nil {
+			return err
+		}
+		return d.updateMode(ctx, func(m *serial.Mode) { set(m, t) })
+	}
+}
+
+// serialMode returns a copy of the port's serial settings, or nil if unknown.
+func (d *Device) serialMode() *serial.Mode {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.mode == nil {
+		return nil
+	}
+	mode := *d.mode
+	return &mode
+}
+
+// updateMode changes the port's serial settings using update, once any
+// transaction in progress has finished.
+func (d *Device) updateMode(ctx context.Context, update func(*serial.Mode)) error {
+	release, err := d.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	start := time.Now()
+	mode := d.serialMode()
+	if mode == nil {
+		return fmt.Errorf("%w: serial settings of port unknown", ErrUnsupportedAttribute)
+	}
+	update(mode)
+	if err := validateMode(mode); err != nil {
+		return err
+	}
+	if err := d.port.SetMode(mode); err != nil {
+		return d.opError("configure", "", 0, start, err)
+	}
+	d.mu.Lock()
+	d.logger.Debug("serial mode changed", "old", d.mode, "new", mode)
+	d.mode = mode
+	d.mu.Unlock()
+	return nil
+}
+
+// validateMode checks serial settings using the same rules as the baud and
+// dataflow of a VISA resource string.
+func validateMode(m *serial.Mode) error {
+	fail := func(reason string) error {
+		return fmt.Errorf("%w: %s", ErrInvalidAttributeValue, reason)
+	}
+	switch {
+	case m.BaudRate <= 0:
+		return fail(fmt.Sprintf("invalid baud %d", m.BaudRate))
+	case m.DataBits < 5 || m.DataBits > 8:
+		return fail("data bits must be 5, 6, 7, or 8")
+	case m.Parity < serial.NoParity || m.Parity > serial.SpaceParity:
+		return fail(fmt.Sprintf("invalid parity %d", m.Parity))
+	case m.StopBits < serial.OneStopBit || m.StopBits > serial.TwoStopBits:
+		return fail(fmt.Sprintf("invalid stop bits %d", m.StopBits))
+	case m.StopBits == serial.OnePointFiveStopBits && m.DataBits != 5:
+		return fail("1.5 stop bits requires 5 data bits")
+	case m.StopBits == serial.TwoStopBits && m.DataBits == 5:
+		return fail("2 stop bits cannot be used with 5 data bits")
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gotmc/asrl/asrltest"
+	"go.bug.st/serial"
+)
+
+func TestAttributeRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		attr  Attribute
+		value any
+	}{
+		{AttrTMOValue, 2 * time.Second},
+		{AttrTermChar, byte('\r')},
+		{AttrTermCharEn, false},
+		{AttrASRLFlowCntrl, FlowXONXOFF},
+		{AttrASRLBreakLen, 250 * time.Millisecond},
+		{AttrWriteTermination, "\r\n"},
+		{AttrReadTermination, "\r\n"},
+		{AttrStripReadTermination, true},
+		{AttrDelayTime, 10 * time.Millisecond},
+		{AttrErrorChecking, true},
+		{AttrClearString, "*CLS\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.attr.String(), func(t *testing.T) {
+			t.Parallel()
+			d, err := NewDeviceFromPort(asrltest.NewInstrument())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := d.SetAttribute(context.Background(), tc.attr, tc.value); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := d.GetAttribute(tc.attr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.value {
+				t.Errorf("GetAttribute(%s) = %v, want %v", tc.attr, got, tc.value)
+			}
+		})
+	}
+}
+
+func TestAttributeSettersApply(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewDeviceFromPort(asrltest.NewInstrument())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	if err := d.SetAttribute(ctx, AttrTermChar, byte('\r')); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := d.ReadTermination(); got != "\r" {
+		t.Errorf("ReadTermination() = %q, want %q", got, "\r")
+	}
+	policy := RetryPolicy{MaxAttempts: 3}
+	if err := d.SetAttribute(ctx, AttrRetryPolicy, policy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := d.RetryPolicy(); got.MaxAttempts != 3 {
+		t.Errorf("RetryPolicy() = %+v, want %+v", got, policy)
+	}
+}
+
+func TestAttributeSerialMode(t *testing.T) {
+	ports := newFakeUSBPorts(t)
+	inst := asrltest.NewInstrument()
+	ports.plug("/dev/ttyUSB0", "FT1234", inst)
+	const address = "ASRL::/dev/ttyUSB0::9600::7E1::INSTR"
+	d, err := NewDevice(context.Background(), address, WithDelayTime(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	want := map[Attribute]any{
+		AttrRsrcName:     address,
+		AttrRsrcClass:    "INSTR",
+		AttrIntfType:     "ASRL",
+		AttrIntfNum:      0,
+		AttrIntfInstName: "/dev/ttyUSB0",
+		AttrASRLBaud:     9600,
+		AttrASRLDataBits: 7,
+		AttrASRLParity:   serial.EvenParity,
+		AttrASRLStopBits: serial.OneStopBit,
+	}
+	for attr, value := range want {
+		if got, err := d.GetAttribute(attr); err != nil || got != value {
+			t.Errorf("GetAttribute(%s) = %v, %v, want %v", attr, got, err, value)
+		}
+	}
+
+	ctx := context.Background()
+	if err := d.SetAttribute(ctx, AttrASRLBaud, 115200); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.SetAttribute(ctx, AttrASRLDataBits, 8); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.SetAttribute(ctx, AttrASRLParity, serial.NoParity); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.SetAttribute(ctx, AttrASRLStopBits, serial.TwoStopBits); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mode := inst.Mode()
+	wantMode := serial.Mode{
+		BaudRate: 115200,
+		DataBits: 8,
+		Parity:   serial.NoParity,
+		StopBits: serial.TwoStopBits,
+	}
+	if mode.BaudRate != wantMode.BaudRate || mode.DataBits != wantMode.DataBits ||
+		mode.Parity != wantMode.Parity || mode.StopBits != wantMode.StopBits {
+		t.Errorf("Mode() = %+v, want %+v", mode, wantMode)
+	}
+	if got, err := d.GetAttribute(AttrASRLBaud); err != nil || got != 115200 {
+		t.Errorf("GetAttribute(%s) = %v, %v, want 115200", AttrASRLBaud, got, err)
+	}
+	if inst.Closed() {
+		t.Error("port was closed to change the serial settings")
+	}
+}
+
+func TestAttributeSerialModeFromPort(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	d, err := NewDeviceFromPort(inst)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, attr := range []Attribute{AttrRsrcName, AttrIntfInstName, AttrASRLBaud} {
+		if _, err := d.GetAttribute(attr); !errors.Is(err, ErrUnsupportedAttribute) {
+			t.Errorf("GetAttribute(%s) err = %v, want %v", attr, err, ErrUnsupportedAttribute)
+		}
+	}
+	// The other serial settings are unknown, so none can be changed.
+	err = d.SetAttribute(context.Background(), AttrASRLBaud, 19200)
+	if !errors.Is(err, ErrUnsupportedAttribute) {
+		t.Errorf("err = %v, want %v", err, ErrUnsupportedAttribute)
+	}
+	if mode := inst.Mode(); mode.BaudRate != 0 {
+		t.Errorf("Mode() = %+v, want unchanged", mode)
+	}
+}
+
+func TestAttributeModemState(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	inst.SetCTS(false)
+	d, err := NewDeviceFromPort(inst)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, err := d.GetAttribute(AttrASRLCTSState); err != nil || got != false {
+		t.Errorf("GetAttribute(%s) = %v, %v, want false", AttrASRLCTSState, got, err)
+	}
+	if got, err := d.GetAttribute(AttrASRLDSRState); err != nil || got != true {
+		t.Errorf("GetAttribute(%s) = %v, %v, want true", AttrASRLDSRState, got, err)
+	}
+}
+
+func TestAttributeErrors(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewDeviceFromPort(asrltest.NewInstrument())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	testCases := []struct {
+		name  string
+		attr  Attribute
+		value any
+		want  error
+	}{
+		{"unknown", Attribute(99), 1, ErrUnsupportedAttribute},
+		{"read only", AttrASRLCTSState, true, ErrReadOnlyAttribute},
+		{"wrong type", AttrTMOValue, 5, ErrInvalidAttributeValue},
+		{"invalid flow control", AttrASRLFlowCntrl, FlowControl(9), ErrInvalidFlowControl},
+	}
+	for _, tc := range testCases {
+		if err := d.SetAttribute(ctx, tc.attr, tc.value); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+	if _, err := d.GetAttribute(Attribute(99)); !errors.Is(err, ErrUnsupportedAttribute) {
+		t.Errorf("err = %v, want %v", err, ErrUnsupportedAttribute)
+	}
+}
+
+func TestAttributeInvalidSerialMode(t *testing.T) {
+	d, inst := newResourceDevice(t)
+	testCases := []struct {
+		name  string
+		attr  Attribute
+		value any
+	}{
+		{"baud", AttrASRLBaud, -1},
+		{"data bits", AttrASRLDataBits, 9},
+		{"stop bits", AttrASRLStopBits, serial.OnePointFiveStopBits},
+	}
+	for _, tc := range testCases {
+		err := d.SetAttribute(context.Background(), tc.attr, tc.value)
+		if !errors.Is(err, ErrInvalidAttributeValue) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, ErrInvalidAttributeValue)
+		}
+	}
+	if mode := inst.Mode(); mode.BaudRate != 9600 || mode.DataBits != 8 {
+		t.Errorf("Mode() = %+v, want unchanged", mode)
+	}
+}
+
+func TestSetAttributeWaitsForLock(t *testing.T) {
+	d, _ := newResourceDevice(t)
+	lctx, err := d.Lock(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer d.Unlock(lctx)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := d.SetAttribute(ctx, AttrASRLBaud, 19200); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := d.SetAttribute(lctx, AttrASRLBaud, 19200); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// newResourceDevice opens a Device for a simulated instrument on a fake USB
+// serial port at 9600 8N1.
+func newResourceDevice(t *testing.T) (*Device, *asrltest.Instrument) {
+	t.Helper()
+	ports := newFakeUSBPorts(t)
+	inst := asrltest.NewInstrument()
+	ports.plug("/dev/ttyUSB0", "FT1234", inst)
+	d, err := NewDevice(context.Background(), "ASRL::/dev/ttyUSB0::9600::8N1::INSTR",
+		WithDelayTime(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d, inst
+}
+
+func TestAttributeString(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		attr Attribute
+		want string
+	}{
+		{AttrASRLBaud, "VI_ATTR_ASRL_BAUD"},
+		{AttrTMOValue, "VI_ATTR_TMO_VALUE"},
+		{AttrWriteTermination, "WriteTermination"},
+		{Attribute(99), "Attribute(99)"},
+	}
+	for _, tc := range testCases {
+		if got := tc.attr.String(); got != tc.want {
+			t.Errorf("String() = %q, want %q", got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// ErrNotDetected is returned by AutoDetect when no combination of baud rate
+// and dataflow produced a valid response to the probe.
+var ErrNotDetected = errors.New("asrl: no instrument detected")
+
+// errInvalidProbeResponse is returned when a probe response is empty or isn't
+// printable.
+var errInvalidProbeResponse = errors.New("asrl: invalid probe response")
+
+// autoDetectBauds and autoDetectDataflows are the settings tried by
+// AutoDetect, most common first.
+var (
+	autoDetectBauds     = []int{9600, 19200, 38400, 57600, 115200, 4800, 2400, 1200, 300}
+	autoDetectDataflows = []string{"8N1", "8N2", "7E1", "7O1", "7E2", "7O2", "8E1", "8O1"}
+)
+
+// defaultProbeTimeout is how long to wait for each probe response unless
+// overridden using WithReadTimeout. It only applies while detecting, so the
+// Device returned by AutoDetect has the usual default read timeout.
+const defaultProbeTimeout = 500 * time.Millisecond
+
+// AutoDetect finds the serial settings of the instrument connected to the given
+// port by sending the probe query, such as *IDN?, at each common baud rate
+// (9600, 19200, 38400, 57600, 115200, 4800, 2400, 1200, and 300) and dataflow
+// (8N1, 8N2, 7E1, 7O1, 7E2, 7O2, 8E1, and 8O1) in turn. A response is valid if
+// it is printable and ends with the read termination. The port can be given as
+// an operating system port name, such as /dev/ttyUSB0, or as a VISA resource
+// string, such as ASRL1::INSTR, in which case only its address is used.
+//
+// AutoDetect returns a Device, configured with the given DeviceOption values,
+// that is ready to use with the first working settings. The detected settings
+// are available from the Device's Resource method. Each probe waits for the
+// read timeout set using WithReadTimeout, or 500 ms if none is set, so trying
+// every combination can take over half a minute. The returned Device has the
+// read timeout set using WithReadTimeout, or the usual default of five
+// seconds.
+func AutoDetect(
+	ctx context.Context,
+	port, probe string,
+	opts ...DeviceOption,
+) (*Device, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	address := port
+	if v, err := NewVisaResource(port); err == nil {
+		address = v.address
+	}
+
+	start := time.Now()
+	sp, err := openPort(address, &serial.Mode{
+		BaudRate: autoDetectBauds[0],
+		DataBits: 8,
+		Parity:   serial.NoParity,
+		StopBits: serial.OneStopBit,
+	})
+	if err != nil {
+		return nil, &OpError{Op: "open", Resource: port, Elapsed: time.Since(start), Err: err}
+	}
+	d, err := newDevice(sp, nil, opts...)
+	if err != nil {
+		_ = sp.Close()
+		return nil, &OpError{Op: "open", Resource: port, Elapsed: time.Since(start), Err: err}
+	}
+	readTimeout := d.ReadTimeout()
+	if readTimeout == defaultReadTimeout {
+		d.SetReadTimeout(defaultProbeTimeout)
+	}
+
+	for _, baud := range autoDetectBauds {
+		for _, dataflow := range autoDetectDataflows {
+			v, err := NewVisaResource(
+				fmt.Sprintf("ASRL::%s::%d::%s::INSTR", address, baud, dataflow))
+			if err != nil {
+				_ = d.Close()
+				return nil, err
+			}
+			resp, err := d.probeMode(ctx, v, probe)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				_ = d.Close()
+				return nil, ctxErr
+			}
+			if err != nil {
+				d.logger.DebugContext(ctx, "auto detect attempt failed",
+					"baud", baud, "dataflow", dataflow, "err", err)
+				continue
+			}
+			d.resource = v
+			d.mode = v.mode()
+			d.SetReadTimeout(readTimeout)
+			if d.reconnect {
+				d.enableReconnect(sp, address)
+			}
+			d.logger = d.logger.With("resource", v.String())
+			d.logger.DebugContext(ctx, "auto detect succeeded", "response", resp)
+			return d, nil
+		}
+	}
+	_ = d.Close()
+	return nil, fmt.Errorf("%w on %s", ErrNotDetected, address)
+}
+
+// probeMode switches the port to the serial settings of v and sends the probe.
+func (d *Device) probeMode(ctx context.Context, v *VisaResource, probe string) (string, error) {
+	if err := d.port.SetMode(v.mode()); err != nil {
+		return "", err
+	}
+	// A probe sent with the wrong settings leaves garbage in the instrument's
+	// input buffer, so send a lone write termination to end it before probing.
+	if _, err := d.WriteBinary(ctx, []byte(d.WriteTermination())); err != nil {
+		return "", err
+	}
+	if err := sleepContext(ctx, d.DelayTime()); err != nil {
+		return "", err
+	}
+	if err := d.port.ResetInputBuffer(); err != nil {
+		return "", err
+	}
+	d.reader.Reset(d.in)
+	return d.probe(ctx, probe)
+}
+
+// probe sends the query, waiting up to ReadTimeout for the response, and
+// returns the response with surrounding whitespace trimmed if it is valid.
+func (d *Device) probe(ctx context.Context, query string) (string, error) {
+	qctx, cancel := context.WithTimeout(ctx, d.ReadTimeout())
+	defer cancel()
+	resp, err := d.Query(qctx, query)
+	if err != nil {
+		return "", err
+	}
+	if !validProbeResponse(resp) {
+		return "", fmt.Errorf("%w %q", errInvalidProbeResponse, resp)
+	}
+	return strings.TrimSpace(resp), nil
+}
+
+// validProbeResponse reports whether resp is nonempty printable ASCII, once
+// surrounding whitespace is trimmed. The read termination is checked by Query.
+func validProbeResponse(resp string) bool {
+	line := strings.TrimSpace(resp)
+	if line == "" {
+		return false
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if (c < ' ' || c > '~') && c != '\r' && c != '\n' && c != '\t' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gotmc/asrl/asrltest"
+	"go.bug.st/serial"
+)
+
+func TestValidProbeResponse(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		resp string
+		want bool
+	}{
+		{resp: "HEWLETT-PACKARD,34401A,0,11-5-2\n", want: true},
+		{resp: "ACME,1\r\n", want: true},
+		{resp: "ACME,1", want: true},
+		{resp: "\n", want: false},
+		{resp: " \r\n", want: false},
+		{resp: "", want: false},
+		{resp: "\xff\xfe\x80\n", want: false},
+		{resp: "AC\x00ME\n", want: false},
+	} {
+		if got := validProbeResponse(tc.resp); got != tc.want {
+			t.Errorf("validProbeResponse(%q) = %t, want %t", tc.resp, got, tc.want)
+		}
+	}
+}
+
+func TestAutoDetect(t *testing.T) {
+	var inst *asrltest.Instrument
+	fakeSerialLibrary(t, nil, nil, func(name string, mode *serial.Mode) (serial.Port, error) {
+		if name != "/dev/ttyUSB0" {
+			return nil, errors.New("no such port")
+		}
+		inst = asrltest.NewInstrument()
+		inst.HandleFunc("*IDN?", func([]string) string {
+			m := inst.Mode()
+			if m.BaudRate != 19200 || m.DataBits != 7 || m.Parity != serial.OddParity {
+				return "\xf3\x80\xfe"
+			}
+			return "HEWLETT-PACKARD,34401A,0,11-5-2"
+		})
+		return inst, nil
+	})
+
+	d, err := AutoDetect(context.Background(), "ASRL/dev/ttyUSB0::INSTR", "*IDN?",
+		WithDelayTime(0),
+		WithReadTimeout(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	v := d.Resource()
+	if v == nil {
+		t.Fatal("Resource = nil")
+	}
+	if v.Baud() != 19200 || v.DataBits() != 7 || v.Parity() != serial.OddParity ||
+		v.StopBits() != serial.OneStopBit {
+		t.Errorf("detected %s, want 19200 baud 7O1", v)
+	}
+	if want := "ASRL::/dev/ttyUSB0::19200::7O1::INSTR"; v.String() != want {
+		t.Errorf("Resource = %q, want %q", v, want)
+	}
+	if got := d.ReadTimeout(); got != 10*time.Millisecond {
+		t.Errorf("ReadTimeout() = %v, want %v", got, 10*time.Millisecond)
+	}
+	got, err := d.Query(context.Background(), "*IDN?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "HEWLETT-PACKARD,34401A,0,11-5-2\n"; got != want {
+		t.Errorf("Query = %q, want %q", got, want)
+	}
+}
+
+func TestAutoDetectDefaultReadTimeout(t *testing.T) {
+	fakeSerialLibrary(t, nil, nil, func(string, *serial.Mode) (serial.Port, error) {
+		inst := asrltest.NewInstrument()
+		inst.Handle("*IDN?", "ACME,1")
+		return inst, nil
+	})
+	d, err := AutoDetect(context.Background(), "/dev/ttyUSB0", "*IDN?", WithDelayTime(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer d.Close()
+	if got := d.ReadTimeout(); got != defaultReadTimeout {
+		t.Errorf("ReadTimeout() = %v, want %v", got, defaultReadTimeout)
+	}
+}
+
+func TestAutoDetectNotDetected(t *testing.T) {
+	origBauds, origDataflows := autoDetectBauds, autoDetectDataflows
+	t.Cleanup(func() { autoDetectBauds, autoDetectDataflows = origBauds, origDataflows })
+	autoDetectBauds = []int{9600, 19200}
+	autoDetectDataflows = []string{"8N1", "7E1"}
+
+	var inst *asrltest.Instrument
+	fakeSerialLibrary(t, nil, nil, func(string, *serial.Mode) (serial.Port, error) {
+		inst = asrltest.NewInstrument()
+		return inst, nil
+	})
+	_, err := AutoDetect(context.Background(), "/dev/ttyUSB0", "*IDN?",
+		WithDelayTime(0),
+		WithReadTimeout(time.Millisecond),
+	)
+	if !errors.Is(err, ErrNotDetected) {
+		t.Fatalf("err = %v, want %v", err, ErrNotDetected)
+	}
+	if !inst.Closed() {
+		t.Error("port was not closed")
+	}
+}
+
+func TestAutoDetectCanceled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := AutoDetect(ctx, "/dev/ttyUSB0", "*IDN?"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
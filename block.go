@@ -0,0 +1,266 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidBlock is returned when a response isn't a valid IEEE 488.2
+// arbitrary block.
+var ErrInvalidBlock = errors.New("asrl: invalid binary block")
+
+// blockChunkSize is the largest read made while reading a block's payload, so
+// that a corrupt header can't cause a huge allocation up front.
+const blockChunkSize = 64 * 1024
+
+// blockWriteChunkSize is the largest write made while sending a block, so that
+// flow control is checked often enough for instruments with small input
+// buffers.
+const blockWriteChunkSize = 256
+
+// CommandBinaryBlock sends the given command prefix followed by data as an
+// IEEE 488.2 definite length arbitrary block, #<n><length><data>, and the
+// write termination. The prefix is sent as is, so it must include any
+// separator the instrument expects before the block, such as "DATA:DAC
+// VOLATILE, ". Any checksum the instrument expects must be included in data.
+//
+// The message is written in chunks, waiting for the instrument to be ready
+// according to the flow control method before each chunk, and DelayTime is
+// waited after the last chunk, as with Command. If error checking is enabled,
+// the instrument's error queue is then drained. Use EncodeInt16s and
+// EncodeFloat32s to send typed values.
+func (d *Device) CommandBinaryBlock(ctx context.Context, prefix string, data []byte) error {
+	release, err := d.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	start := time.Now()
+	length := strconv.Itoa(len(data))
+	if len(length) > 9 {
+		return fmt.Errorf("%w: %d bytes is too long", ErrInvalidBlock, len(data))
+	}
+	term := d.WriteTermination()
+	msg := make([]byte, 0, len(prefix)+2+len(length)+len(data)+len(term))
+	msg = append(msg, prefix...)
+	msg = append(msg, '#', byte('0'+len(length)))
+	msg = append(msg, length...)
+	msg = append(msg, data...)
+	msg = append(msg, term...)
+
+	for sent := 0; sent < len(msg); {
+		if err := d.waitForFlowControl(ctx); err != nil {
+			return d.opError("handshake", prefix, sent, start, err)
+		}
+		n, err := d.writeBinary(ctx, msg[sent:min(sent+blockWriteChunkSize, len(msg))])
+		sent += n
+		if err != nil {
+			d.logger.DebugContext(ctx, "binary block command failed", "prefix", prefix,
+				"n", len(data), "sent", sent, "elapsed", time.Since(start), "err", err)
+			return d.opError("write", prefix, sent, start, err)
+		}
+	}
+	d.logger.DebugContext(ctx, "binary block command",
+		"prefix", prefix, "n", len(data), "elapsed", time.Since(start))
+
+	if err := sleepContext(ctx, d.DelayTime()); err != nil {
+		return d.opError("write", prefix, len(msg), start, err)
+	}
+	if d.ErrorChecking() {
+		return d.drainErrors(ctx, strings.TrimSpace(prefix))
+	}
+	return nil
+}
+
+// QueryBinaryBlock sends the given command and reads the IEEE 488.2 arbitrary
+// block response using ReadBinaryBlock, such as for fetching a waveform with
+// CURV? or a screen dump.
+func (d *Device) QueryBinaryBlock(ctx context.Context, cmd string) ([]byte, error) {
+	release, err := d.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := d.command(ctx, cmd); err != nil {
+		// The command may have been sent before the write failed or the context
+		// was done, in which case the block is still to come.
+		d.stale = true
+		return nil, err
+	}
+	return d.readBinaryBlock(ctx, cmd)
+}
+
+// ReadBinaryBlock reads an IEEE 488.2 arbitrary block and returns its payload.
+// A definite length block has the form #<n><length><data>, where n is the
+// number of digits in length, such as #15HELLO, and an indefinite length block
+// has the form #0<data>. Since a serial port has no END signal, an indefinite
+// length block ends at the read termination, which is removed from the payload,
+// so the payload must not contain the read termination. If TermCharEnabled is
+// false, an indefinite length block instead ends when no more data arrives
+// within the read timeout. The read termination following a definite length
+// block is consumed, if TermCharEnabled is true.
+//
+// The context is used for cancellation; if the context is canceled while
+// reading, ReadBinaryBlock returns the context error.
+func (d *Device) ReadBinaryBlock(ctx context.Context) ([]byte, error) {
+	release, err := d.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return d.readBinaryBlock(ctx, "")
+}
+
+// readBinaryBlock reads an arbitrary block, which is the response to cmd if
+// cmd isn't empty. The caller must hold the lock.
+func (d *Device) readBinaryBlock(ctx context.Context, cmd string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	d.beginRead(ctx, d.ReadTimeout())
+	defer d.endRead()
+	data, err := d.readBlock(ctx)
+	if err != nil {
+		d.stale = true
+	}
+	n := len(data)
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		data, err = nil, ctxErr
+	}
+	err = d.opError("read", cmd, n, start, err)
+	d.logger.DebugContext(ctx, "binary block read",
+		"n", len(data), "elapsed", time.Since(start), "err", err)
+	return data, err
+}
+
+// readBlock reads an arbitrary block from the buffered reader.
+func (d *Device) readBlock(ctx context.Context) ([]byte, error) {
+	header, err := d.readBlockBytes(ctx, 2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0] != '#' || header[1] < '0' || header[1] > '9' {
+		return nil, fmt.Errorf("%w: header %q", ErrInvalidBlock, header)
+	}
+
+	term := d.readTermination()
+	if header[1] == '0' {
+		var s string
+		if term != "" {
+			s, err = readUntil(d.reader, term)
+			s = strings.TrimSuffix(s, term)
+		} else {
+			s, err = readUntilQuiet(d.reader)
+		}
+		return []byte(s), err
+	}
+
+	digits, err := d.readBlockBytes(ctx, int(header[1]-'0'))
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(string(digits))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("%w: length %q", ErrInvalidBlock, digits)
+	}
+	data, err := d.readBlockBytes(ctx, length)
+	if err != nil {
+		return data, err
+	}
+
+	if term != "" {
+		got, err := d.readBlockBytes(ctx, len(term))
+		if err != nil {
+			return data, err
+		}
+		if string(got) != term {
+			return data, fmt.Errorf("%w: got %q after block, want read termination %q",
+				ErrInvalidBlock, got, term)
+		}
+	}
+	return data, nil
+}
+
+// readBlockBytes reads exactly n bytes from the buffered reader, returning a
+// *TimeoutError if the read timeout elapses first.
+func (d *Device) readBlockBytes(ctx context.Context, n int) ([]byte, error) {
+	buf := make([]byte, 0, min(n, blockChunkSize))
+	for len(buf) < n {
+		if err := ctx.Err(); err != nil {
+			return buf, err
+		}
+		if len(buf) == cap(buf) {
+			buf = slices.Grow(buf, min(n-len(buf), blockChunkSize))
+		}
+		m, err := d.reader.Read(buf[len(buf):min(n, cap(buf))])
+		buf = buf[:len(buf)+m]
+		if err != nil {
+			return buf, d.timeoutError(err, buf)
+		}
+	}
+	return buf, nil
+}
+
+// DecodeInt16s decodes the payload of a binary block into 16-bit integers
+// using the given byte order, such as binary.BigEndian, which is the IEEE
+// 488.2 default.
+func DecodeInt16s(data []byte, order binary.ByteOrder) ([]int16, error) {
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("%w: %d bytes is not a multiple of 2", ErrInvalidBlock, len(data))
+	}
+	values := make([]int16, len(data)/2)
+	for i := range values {
+		values[i] = int16(order.Uint16(data[2*i:]))
+	}
+	return values, nil
+}
+
+// DecodeFloat32s decodes the payload of a binary block into IEEE 754 32-bit
+// floating point numbers using the given byte order, such as binary.BigEndian,
+// which is the IEEE 488.2 default.
+func DecodeFloat32s(data []byte, order binary.ByteOrder) ([]float32, error) {
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("%w: %d bytes is not a multiple of 4", ErrInvalidBlock, len(data))
+	}
+	values := make([]float32, len(data)/4)
+	for i := range values {
+		values[i] = math.Float32frombits(order.Uint32(data[4*i:]))
+	}
+	return values, nil
+}
+
+// EncodeInt16s encodes 16-bit integers for sending as the payload of a binary
+// block using the given byte order, such as binary.BigEndian, which is the
+// IEEE 488.2 default.
+func EncodeInt16s(values []int16, order binary.ByteOrder) []byte {
+	data := make([]byte, 2*len(values))
+	for i, v := range values {
+		order.PutUint16(data[2*i:], uint16(v))
+	}
+	return data
+}
+
+// EncodeFloat32s encodes IEEE 754 32-bit floating point numbers for sending as
+// the payload of a binary block using the given byte order, such as
+// binary.BigEndian, which is the IEEE 488.2 default.
+func EncodeFloat32s(values []float32, order binary.ByteOrder) []byte {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		order.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return data
+}
@@ -0,0 +1,285 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gotmc/asrl/asrltest"
+)
+
+func TestQueryBinaryBlock(t *testing.T) {
+	t.Parallel()
+
+	large := strings.Repeat("0123456789", 10000)
+	testCases := []struct {
+		name     string
+		response string
+		opts     []DeviceOption
+		want     string
+		wantErr  error
+	}{
+		{name: "definite", response: "#15HELLO", want: "HELLO"},
+		{name: "definite with terminator in data", response: "#14A\nB\n", want: "A\nB\n"},
+		{name: "empty", response: "#10", want: ""},
+		{name: "large", response: "#6100000" + large, want: large},
+		{name: "indefinite", response: "#0HELLO", want: "HELLO"},
+		{
+			name:     "indefinite with termchar disabled",
+			response: "#0HELLO",
+			opts:     []DeviceOption{WithTermCharEnabled(false)},
+			want:     "HELLO\n",
+		},
+		{
+			name:     "CR LF read termination",
+			response: "#15HELLO\r",
+			opts:     []DeviceOption{WithReadTermination("\r\n")},
+			want:     "HELLO",
+		},
+		{name: "missing hash", response: "15HELLO", wantErr: ErrInvalidBlock},
+		{name: "invalid length", response: "#2x5HELLO", wantErr: ErrInvalidBlock},
+		{name: "data after block", response: "#13HELLO", wantErr: ErrInvalidBlock},
+		{name: "short block", response: "#19HELLO", wantErr: ErrTimeout},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			inst := asrltest.NewInstrument()
+			inst.Handle("CURV?", tc.response)
+			opts := append([]DeviceOption{
+				WithDelayTime(time.Millisecond),
+				WithReadTimeout(20 * time.Millisecond),
+			}, tc.opts...)
+			d, err := NewDeviceFromPort(inst, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := d.QueryBinaryBlock(context.Background(), "CURV?")
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr == nil && string(got) != tc.want {
+				t.Errorf("QueryBinaryBlock() = %q, want %q",
+					truncate(string(got)), truncate(tc.want))
+			}
+		})
+	}
+}
+
+func TestReadBinaryBlockCanceled(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	inst.Send("#15HE")
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(5*time.Second),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = d.ReadBinaryBlock(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ReadBinaryBlock took %s after cancel", elapsed)
+	}
+}
+
+func TestQueryBinaryBlockCanceledAfterWrite(t *testing.T) {
+	t.Parallel()
+	inst := asrltest.NewInstrument()
+	inst.Handle("CURV?", "#15HELLO")
+	inst.Handle("*IDN?", "ACME")
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(50*time.Millisecond),
+		WithReadTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The context is done during the delay after the command is sent.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := d.QueryBinaryBlock(ctx, "CURV?"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	got, err := d.Query(context.Background(), "*IDN?")
+	if err != nil || got != "ACME\n" {
+		t.Errorf("Query() = %q, %v, want %q", got, err, "ACME\n")
+	}
+}
+
+func TestCommandBinaryBlock(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "empty", data: nil, want: "DATA #10\n"},
+		{name: "short", data: []byte("A\nB"), want: "DATA #13A\nB\n"},
+		{
+			name: "long",
+			data: bytes.Repeat([]byte{0xff}, 600),
+			want: "DATA #3600" + strings.Repeat("\xff", 600) + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mp := newMockPort("")
+			d := newTestDevice(mp)
+			if err := d.CommandBinaryBlock(context.Background(), "DATA ", tc.data); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := mp.writeBuf.String(); got != tc.want {
+				t.Errorf("written = %q, want %q", truncate(got), truncate(tc.want))
+			}
+		})
+	}
+}
+
+func TestCommandBinaryBlockChunks(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	d, err := NewDeviceFromPort(inst,
+		WithFlowControl(FlowRTSCTS),
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data := EncodeInt16s(make([]int16, 300), binary.BigEndian)
+	if err := d.CommandBinaryBlock(context.Background(), "LDWF ", data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var writes []int
+	for _, r := range inst.Transcript() {
+		if r.Direction == asrltest.FromHost {
+			writes = append(writes, len(r.Data))
+		}
+	}
+	// "LDWF #3600" + 600 bytes + "\n" is 611 bytes.
+	if want := []int{256, 256, 99}; !slices.Equal(writes, want) {
+		t.Errorf("write sizes = %v, want %v", writes, want)
+	}
+
+	inst.SetCTS(false)
+	err = d.CommandBinaryBlock(context.Background(), "LDWF ", data)
+	if !errors.Is(err, ErrCTSNotReady) {
+		t.Errorf("err = %v, want %v", err, ErrCTSNotReady)
+	}
+}
+
+func TestCommandBinaryBlockCanceled(t *testing.T) {
+	t.Parallel()
+
+	mp := newMockPort("")
+	d := newTestDevice(mp)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := d.CommandBinaryBlock(ctx, "DATA ", []byte{1, 2})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if mp.writeBuf.Len() != 0 {
+		t.Error("expected no data written when context is canceled")
+	}
+}
+
+func TestEncodeInt16s(t *testing.T) {
+	t.Parallel()
+
+	values := []int16{1, -2}
+	got := EncodeInt16s(values, binary.BigEndian)
+	if want := []byte{0x00, 0x01, 0xff, 0xfe}; !bytes.Equal(got, want) {
+		t.Errorf("EncodeInt16s(BigEndian) = % x, want % x", got, want)
+	}
+	got = EncodeInt16s(values, binary.LittleEndian)
+	if want := []byte{0x01, 0x00, 0xfe, 0xff}; !bytes.Equal(got, want) {
+		t.Errorf("EncodeInt16s(LittleEndian) = % x, want % x", got, want)
+	}
+}
+
+func TestEncodeFloat32s(t *testing.T) {
+	t.Parallel()
+
+	values := []float32{1.5, -0.25}
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		got, err := DecodeFloat32s(EncodeFloat32s(values, order), order)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(got, values) {
+			t.Errorf("round trip with %v = %v, want %v", order, got, values)
+		}
+	}
+}
+
+func TestDecodeInt16s(t *testing.T) {
+	t.Parallel()
+
+	data := []byte{0x00, 0x01, 0xff, 0xfe}
+	got, err := DecodeInt16s(data, binary.BigEndian)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []int16{1, -2}; !slices.Equal(got, want) {
+		t.Errorf("DecodeInt16s(BigEndian) = %v, want %v", got, want)
+	}
+	got, err = DecodeInt16s(data, binary.LittleEndian)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []int16{256, -257}; !slices.Equal(got, want) {
+		t.Errorf("DecodeInt16s(LittleEndian) = %v, want %v", got, want)
+	}
+	if _, err := DecodeInt16s(data[:3], binary.BigEndian); !errors.Is(err, ErrInvalidBlock) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidBlock)
+	}
+}
+
+func TestDecodeFloat32s(t *testing.T) {
+	t.Parallel()
+
+	want := []float32{1.5, -0.25}
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, want)
+	got, err := DecodeFloat32s(buf.Bytes(), binary.LittleEndian)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("DecodeFloat32s() = %v, want %v", got, want)
+	}
+	_, err = DecodeFloat32s(buf.Bytes()[:6], binary.LittleEndian)
+	if !errors.Is(err, ErrInvalidBlock) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidBlock)
+	}
+}
+
+// truncate shortens s for use in test failure messages.
+func truncate(s string) string {
+	if len(s) > 40 {
+		return s[:40] + "..."
+	}
+	return s
+}
@@ -0,0 +1,289 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// Sentinel errors returned when reading and replaying wire captures.
+var (
+	ErrInvalidCapture = errors.New("asrl: invalid capture line")
+	ErrReplayMismatch = errors.New("asrl: replay mismatch")
+)
+
+// Direction identifies whether captured data was transmitted to or received
+// from the instrument.
+type Direction byte
+
+// Available capture directions. The values are the characters used in the
+// capture format.
+const (
+	Transmitted Direction = '>'
+	Received    Direction = '<'
+)
+
+// String returns the capture format character for the direction.
+func (d Direction) String() string {
+	return string(d)
+}
+
+// CaptureRecord is a single chunk of data captured on the wire.
+type CaptureRecord struct {
+	Time      time.Time
+	Direction Direction
+	Data      []byte
+}
+
+// WithCapture logs every chunk transmitted to and received from the serial
+// port to w, one chunk per line, in the format:
+//
+//	<timestamp> <direction> <data>
+//
+// The timestamp is in RFC 3339 format with nanoseconds in UTC. The direction
+// is ">" for data transmitted to the instrument and "<" for data received from
+// it. The data is a double-quoted Go string literal, so binary data and
+// terminators are escaped. For example:
+//
+//	2026-10-17T14:03:07.519278000Z > "*IDN?\n"
+//	2026-10-17T14:03:07.561904000Z < "Stanford Research Systems,DS345,0,1.0\n"
+//
+// Blank lines and lines starting with "#" are ignored by ReadCapture. Errors
+// writing to w are ignored so that capturing never disrupts the session.
+func WithCapture(w io.Writer) DeviceOption {
+	return func(d *Device) {
+		d.capture = w
+	}
+}
+
+// capturePort wraps a serial.Port and logs every chunk read or written.
+type capturePort struct {
+	serial.Port
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (p *capturePort) Read(b []byte) (int, error) {
+	n, err := p.Port.Read(b)
+	p.log(Received, b[:n])
+	return n, err
+}
+
+func (p *capturePort) Write(b []byte) (int, error) {
+	n, err := p.Port.Write(b)
+	p.log(Transmitted, b[:n])
+	return n, err
+}
+
+func (p *capturePort) log(dir Direction, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	line := formatCaptureRecord(CaptureRecord{
+		Time:      time.Now(),
+		Direction: dir,
+		Data:      data,
+	})
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, _ = io.WriteString(p.w, line)
+}
+
+func formatCaptureRecord(r CaptureRecord) string {
+	return fmt.Sprintf("%s %s %s\n",
+		r.Time.UTC().Format(captureTimeFormat), r.Direction, strconv.Quote(string(r.Data)))
+}
+
+// captureTimeFormat is RFC 3339 with a fixed-width nanosecond field so that
+// captures line up when viewed.
+const captureTimeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// ReadCapture parses a capture written using WithCapture.
+func ReadCapture(r io.Reader) ([]CaptureRecord, error) {
+	var records []CaptureRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rec, err := parseCaptureLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w %d: %w", ErrInvalidCapture, lineNum, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading capture: %w", err)
+	}
+	return records, nil
+}
+
+func parseCaptureLine(line string) (CaptureRecord, error) {
+	stamp, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		return CaptureRecord{}, errors.New("missing direction")
+	}
+	t, err := time.Parse(time.RFC3339Nano, stamp)
+	if err != nil {
+		return CaptureRecord{}, err
+	}
+	dir, quoted, ok := strings.Cut(rest, " ")
+	if !ok {
+		return CaptureRecord{}, errors.New("missing data")
+	}
+	if dir != Transmitted.String() && dir != Received.String() {
+		return CaptureRecord{}, fmt.Errorf("unknown direction %q", dir)
+	}
+	data, err := strconv.Unquote(quoted)
+	if err != nil {
+		return CaptureRecord{}, fmt.Errorf("unquoting data: %w", err)
+	}
+	return CaptureRecord{Time: t, Direction: Direction(dir[0]), Data: []byte(data)}, nil
+}
+
+// ReplayPort is a serial.Port that plays back the instrument side of a capture
+// written using WithCapture, so a failing session can be reproduced without
+// hardware. Data written to the ReplayPort must match the transmitted data in
+// the capture, although it may be split into different chunks. Received data
+// is returned by Read once all preceding transmitted data has been written.
+// Capture timestamps are ignored and data is replayed as fast as it is read.
+type ReplayPort struct {
+	mu          sync.Mutex
+	records     []CaptureRecord
+	readTimeout time.Duration
+	closed      bool
+}
+
+// NewReplayPort reads a capture written using WithCapture and returns a
+// ReplayPort that plays it back.
+func NewReplayPort(r io.Reader) (*ReplayPort, error) {
+	records, err := ReadCapture(r)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayPort{records: records, readTimeout: serial.NoTimeout}, nil
+}
+
+// Done reports whether the entire capture has been replayed.
+func (p *ReplayPort) Done() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.records) == 0
+}
+
+// Read returns the next received data in the capture. If the capture expects
+// the host to transmit data first, Read waits for the read timeout and returns
+// zero bytes, just as a silent instrument would. Read returns io.EOF once the
+// capture is exhausted.
+func (p *ReplayPort) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	if len(p.records) == 0 {
+		p.mu.Unlock()
+		return 0, io.EOF
+	}
+	if rec := &p.records[0]; rec.Direction == Received {
+		n := copy(b, rec.Data)
+		rec.Data = rec.Data[n:]
+		if len(rec.Data) == 0 {
+			p.records = p.records[1:]
+		}
+		p.mu.Unlock()
+		return n, nil
+	}
+	timeout := p.readTimeout
+	p.mu.Unlock()
+	if timeout > 0 {
+		time.Sleep(timeout)
+	}
+	return 0, nil
+}
+
+// Write checks the given data against the next transmitted data in the capture
+// and returns an error wrapping ErrReplayMismatch if it differs.
+func (p *ReplayPort) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, io.ErrClosedPipe
+	}
+	var n int
+	for n < len(b) {
+		if len(p.records) == 0 || p.records[0].Direction != Transmitted {
+			return n, fmt.Errorf("%w: unexpected write %q", ErrReplayMismatch, b[n:])
+		}
+		rec := &p.records[0]
+		m := min(len(rec.Data), len(b)-n)
+		if string(rec.Data[:m]) != string(b[n:n+m]) {
+			return n, fmt.Errorf("%w: wrote %q, capture has %q",
+				ErrReplayMismatch, b[n:n+m], rec.Data[:m])
+		}
+		n += m
+		rec.Data = rec.Data[m:]
+		if len(rec.Data) == 0 {
+			p.records = p.records[1:]
+		}
+	}
+	return n, nil
+}
+
+// SetReadTimeout sets how long Read waits when the capture expects the host
+// to transmit next.
+func (p *ReplayPort) SetReadTimeout(t time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.readTimeout = t
+	return nil
+}
+
+// Close closes the ReplayPort.
+func (p *ReplayPort) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+// SetMode is a no-op for a ReplayPort.
+func (p *ReplayPort) SetMode(_ *serial.Mode) error { return nil }
+
+// Drain is a no-op for a ReplayPort.
+func (p *ReplayPort) Drain() error { return nil }
+
+// ResetInputBuffer is a no-op for a ReplayPort.
+func (p *ReplayPort) ResetInputBuffer() error { return nil }
+
+// ResetOutputBuffer is a no-op for a ReplayPort.
+func (p *ReplayPort) ResetOutputBuffer() error { return nil }
+
+// SetDTR is a no-op for a ReplayPort.
+func (p *ReplayPort) SetDTR(_ bool) error { return nil }
+
+// SetRTS is a no-op for a ReplayPort.
+func (p *ReplayPort) SetRTS(_ bool) error { return nil }
+
+// Break is a no-op for a ReplayPort.
+func (p *ReplayPort) Break(_ time.Duration) error { return nil }
+
+// GetModemStatusBits reports all modem status bits as asserted, since a capture
+// does not record them.
+func (p *ReplayPort) GetModemStatusBits() (*serial.ModemStatusBits, error) {
+	return &serial.ModemStatusBits{CTS: true, DSR: true, DCD: true}, nil
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCapture(t *testing.T) {
+	t.Parallel()
+	mp := newMockPort("Stanford Research Systems,DS345\n")
+	var capture bytes.Buffer
+	d, err := NewDeviceFromPort(mp,
+		WithCapture(&capture),
+		WithDelayTime(1*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := d.Query(ctx, "*IDN?"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := d.WriteBinary(ctx, []byte{0x00, 0xff}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, err := ReadCapture(&capture)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\ncapture:\n%s", err, capture.String())
+	}
+	want := []struct {
+		dir  Direction
+		data string
+	}{
+		{Transmitted, "*IDN?\n"},
+		{Received, "Stanford Research Systems,DS345\n"},
+		{Transmitted, "\x00\xff"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d", len(records), len(want))
+	}
+	for i, w := range want {
+		if records[i].Direction != w.dir || string(records[i].Data) != w.data {
+			t.Errorf("record %d = %s %q, want %s %q",
+				i, records[i].Direction, records[i].Data, w.dir, w.data)
+		}
+		if records[i].Time.IsZero() {
+			t.Errorf("record %d has zero time", i)
+		}
+	}
+}
+
+func TestReadCapture(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr error
+	}{
+		{
+			name: "comments and blank lines",
+			input: "# session 1\n\n" +
+				"2026-10-17T14:03:07.519278000Z > \"*IDN?\\n\"\n" +
+				"2026-10-17T14:03:07.561904000Z < \"ACME,1\\n\"\n",
+			want: 2,
+		},
+		{
+			name:    "bad timestamp",
+			input:   "yesterday > \"*IDN?\\n\"\n",
+			wantErr: ErrInvalidCapture,
+		},
+		{
+			name:    "bad direction",
+			input:   "2026-10-17T14:03:07.519278000Z = \"*IDN?\\n\"\n",
+			wantErr: ErrInvalidCapture,
+		},
+		{
+			name:    "unquoted data",
+			input:   "2026-10-17T14:03:07.519278000Z > *IDN?\n",
+			wantErr: ErrInvalidCapture,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			records, err := ReadCapture(strings.NewReader(tc.input))
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(records) != tc.want {
+				t.Errorf("got %d records, want %d", len(records), tc.want)
+			}
+		})
+	}
+}
+
+const replayCapture = `2026-10-17T14:03:07.519278000Z > "*IDN"
+2026-10-17T14:03:07.519279000Z > "?\n"
+2026-10-17T14:03:07.561904000Z < "ACME,"
+2026-10-17T14:03:07.561905000Z < "1\n"
+2026-10-17T14:03:07.600000000Z > "*RST\n"
+`
+
+func TestReplayPort(t *testing.T) {
+	t.Parallel()
+	rp, err := NewReplayPort(strings.NewReader(replayCapture))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d, err := NewDeviceFromPort(rp, WithDelayTime(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	got, err := d.Query(ctx, "*IDN?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "ACME,1\n" {
+		t.Errorf("Query = %q, want %q", got, "ACME,1\n")
+	}
+	if rp.Done() {
+		t.Error("Done = true before capture was replayed")
+	}
+	if err := d.Command(ctx, "*RST"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rp.Done() {
+		t.Error("Done = false after capture was replayed")
+	}
+}
+
+func TestReplayPortMismatch(t *testing.T) {
+	t.Parallel()
+	rp, err := NewReplayPort(strings.NewReader(replayCapture))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d, err := NewDeviceFromPort(rp, WithDelayTime(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = d.Command(context.Background(), "*CLS")
+	if !errors.Is(err, ErrReplayMismatch) {
+		t.Fatalf("err = %v, want %v", err, ErrReplayMismatch)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotQuiet is returned by Clear when the instrument keeps sending data for
+// longer than the read timeout.
+var ErrNotQuiet = errors.New("asrl: line not quiet")
+
+// quietTime is how long Clear waits without any data arriving before it
+// considers the line quiet.
+const quietTime = 100 * time.Millisecond
+
+// WithClearBreak sets the duration of the serial break sent by Clear. A zero
+// duration, the default, sends no break.
+func WithClearBreak(t time.Duration) DeviceOption {
+	return func(d *Device) {
+		d.clearBreak = t
+	}
+}
+
+// ClearBreak returns the duration of the serial break sent by Clear, or zero
+// if no break is sent.
+func (d *Device) ClearBreak() time.Duration {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.clearBreak
+}
+
+// SetClearBreak sets the duration of the serial break sent by Clear. A zero
+// duration sends no break.
+func (d *Device) SetClearBreak(t time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clearBreak = t
+}
+
+// WithClearString sets the data sent by Clear to clear the instrument, such as
+// "*CLS\n" or "\x03" (Ctrl-C). It is sent as is, without the write
+// termination. An empty string, the default, sends nothing.
+func WithClearString(s string) DeviceOption {
+	return func(d *Device) {
+		d.clearString = s
+	}
+}
+
+// ClearString returns the data sent by Clear to clear the instrument.
+func (d *Device) ClearString() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.clearString
+}
+
+// SetClearString sets the data sent by Clear to clear the instrument. It is
+// sent as is, without the write termination. An empty string sends nothing.
+func (d *Device) SetClearString(s string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clearString = s
+}
+
+// Clear returns the Device and instrument to a known state after an aborted
+// transfer, similar to a VISA device clear, without closing the port. Clear
+// discards the data in the port's input and output buffers and in the Device's
+// buffered reader, resets the XON/XOFF flow control state, sends the serial
+// break set by WithClearBreak and then the clear string set by
+// WithClearString, if any, and finally reads and discards any data the
+// instrument sends until the line has been quiet for 100 ms. If data keeps
+// arriving for longer than the read timeout, Clear returns ErrNotQuiet.
+func (d *Device) Clear(ctx context.Context) error {
+	release, err := d.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	start := time.Now()
+	if err := d.port.ResetOutputBuffer(); err != nil {
+		return d.opError("clear", "", 0, start, fmt.Errorf("discarding output: %w", err))
+	}
+	if err := d.port.ResetInputBuffer(); err != nil {
+		return d.opError("clear", "", 0, start, fmt.Errorf("discarding input: %w", err))
+	}
+	d.reader.Reset(d.in)
+	d.xon.resume()
+	d.stale, d.unanswered = false, false
+
+	if t := d.ClearBreak(); t > 0 {
+		if err := d.port.Break(t); err != nil {
+			return d.opError("clear", "", 0, start, fmt.Errorf("sending break: %w", err))
+		}
+	}
+	if s := d.ClearString(); s != "" {
+		if n, err := d.writeBinary(ctx, []byte(s)); err != nil {
+			return d.opError("clear", s, n, start, fmt.Errorf("sending clear string: %w", err))
+		}
+	}
+	n, err := d.discardUntilQuiet(ctx)
+	err = d.opError("clear", "", n, start, err)
+	d.logger.DebugContext(ctx, "device clear",
+		"discarded", n, "elapsed", time.Since(start), "err", err)
+	return err
+}
+
+// discardUntilQuiet reads and discards data until none arrives for quietTime,
+// returning the number of bytes discarded. The caller must hold the lock.
+func (d *Device) discardUntilQuiet(ctx context.Context) (int, error) {
+	readTimeout := d.ReadTimeout()
+	deadline := time.Now().Add(readTimeout)
+	d.beginRead(ctx, quietTime)
+	defer d.endRead()
+	buf := make([]byte, 256)
+	total := 0
+	for {
+		n, err := d.reader.Read(buf)
+		total += n
+		if errors.Is(err, ErrTimeout) {
+			return total, nil
+		}
+		if err != nil {
+			d.stale = true
+			return total, err
+		}
+		if readTimeout >= 0 && time.Now().After(deadline) {
+			d.stale = true
+			return total, fmt.Errorf("%w after %s", ErrNotQuiet, readTimeout)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gotmc/asrl/asrltest"
+)
+
+func TestClear(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	inst.Handle("*IDN?", "ACME,1")
+	// The instrument answers the clear string, which Clear discards.
+	inst.Handle("*CLS", "CLEARED")
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(time.Second),
+		WithClearBreak(250*time.Millisecond),
+		WithClearString("*CLS\n"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	// Leave part of a response in the buffered reader and more in the port.
+	inst.Send("stale\nresponse")
+	buf := make([]byte, 2)
+	if _, err := d.ReadBinary(ctx, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := d.Clear(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := inst.Breaks(); got != 1 {
+		t.Errorf("Breaks() = %d, want 1", got)
+	}
+	if got := inst.Commands(); len(got) != 1 || got[0] != "*CLS" {
+		t.Errorf("Commands() = %q, want %q", got, []string{"*CLS"})
+	}
+	got, err := d.Query(ctx, "*IDN?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "ACME,1\n" {
+		t.Errorf("Query() = %q, want %q", got, "ACME,1\n")
+	}
+}
+
+func TestClearDefaults(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	d, err := NewDeviceFromPort(inst, WithDelayTime(time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := d.ClearBreak(); got != 0 {
+		t.Errorf("ClearBreak() = %s, want 0", got)
+	}
+	if got := d.ClearString(); got != "" {
+		t.Errorf("ClearString() = %q, want empty", got)
+	}
+	if err := d.Clear(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := inst.Breaks(); got != 0 {
+		t.Errorf("Breaks() = %d, want 0", got)
+	}
+	if got := inst.Commands(); len(got) != 0 {
+		t.Errorf("Commands() = %q, want none", got)
+	}
+
+	d.SetClearBreak(time.Millisecond)
+	d.SetClearString("\x03")
+	if got := d.ClearBreak(); got != time.Millisecond {
+		t.Errorf("ClearBreak() = %s, want %s", got, time.Millisecond)
+	}
+	if got := d.ClearString(); got != "\x03" {
+		t.Errorf("ClearString() = %q, want %q", got, "\x03")
+	}
+}
+
+func TestClearXOFF(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(50*time.Millisecond),
+		WithFlowControl(FlowXONXOFF),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	inst.Send(string([]byte{'x', XOFF}))
+	buf := make([]byte, 8)
+	if _, err := d.ReadBinary(ctx, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.Command(ctx, "CMD"); !errors.Is(err, ErrXONNotReceived) {
+		t.Fatalf("err = %v, want %v", err, ErrXONNotReceived)
+	}
+	if err := d.Clear(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.Command(ctx, "CMD"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestClearNotQuiet(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(100*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				inst.Send("chatter\n")
+			}
+		}
+	}()
+	if err := d.Clear(context.Background()); !errors.Is(err, ErrNotQuiet) {
+		t.Errorf("err = %v, want %v", err, ErrNotQuiet)
+	}
+}
+
+func TestClearCanceled(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewDeviceFromPort(asrltest.NewInstrument())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := d.Clear(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+}
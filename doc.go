@@ -23,5 +23,26 @@
 //
 //	ASRL::/dev/tty.usbserial-PX484GRU::9600::8N2::INSTR
 //
-// Supported dataflow values are 8N1 (default), 8N2, 7E2, 7E1, and 7O1.
+// The canonical NI-VISA forms, such as ASRL1::INSTR, ASRL/dev/ttyUSB0::INSTR,
+// and COM3, are also accepted, in which case the baud and dataflow default to
+// 9600 and 8N1. Board numbers are mapped to COM<n> on Windows and
+// /dev/ttyS<n-1> on Linux unless overridden using RegisterBoard.
+//
+// The dataflow is made up of the number of data bits (5 to 8), the parity (N
+// for none, E for even, O for odd, M for mark, or S for space), and the number
+// of stop bits (1, 1.5, or 2), such as 8N1 (default), 7E2, or 5N1.5. As with
+// a standard UART, 1.5 stop bits requires 5 data bits, and 2 stop bits cannot
+// be used with 5 data bits.
+//
+// The flow control method, which defaults to none, is set with the
+// WithFlowControl option or the AttrASRLFlowCntrl attribute.
+//
+// A Device is safe for concurrent use. Each Command and Query is an atomic
+// transaction, and Lock gives a goroutine exclusive use of the Device for a
+// sequence of transactions.
+//
+// Errors opening or communicating with a Device are returned as *OpError
+// values, which give the operation, VISA resource string, and command that
+// failed. They wrap the underlying error, so it can still be checked using
+// errors.Is, such as errors.Is(err, asrl.ErrTimeout).
 package asrl
@@ -11,6 +11,7 @@ import (
 	"log"
 
 	"github.com/gotmc/asrl"
+	"go.bug.st/serial"
 )
 
 func Example() {
@@ -62,6 +63,23 @@ func ExampleNewDevice_withOptions() {
 	fmt.Println("opened serial device with hardware handshaking")
 }
 
+func ExampleNewDeviceFromPort() {
+	// Open the serial port directly, for example to use a pseudo-terminal or a
+	// USB bridge that needs custom setup before use.
+	port, err := serial.Open("/dev/ttyUSB0", &serial.Mode{BaudRate: 9600})
+	if err != nil {
+		log.Fatal(err)
+	}
+	dev, err := asrl.NewDeviceFromPort(port, asrl.WithEndMark('\r'))
+	if err != nil {
+		_ = port.Close()
+		log.Fatal(err)
+	}
+	defer dev.Close()
+
+	fmt.Println("wrapped serial port")
+}
+
 func ExampleNewVisaResource() {
 	v, err := asrl.NewVisaResource("ASRL::/dev/tty.usbserial-PX484GRU::9600::8N2::INSTR")
 	if err != nil {
@@ -111,3 +129,19 @@ func ExampleDevice_Query() {
 	}
 	fmt.Println(idn)
 }
+
+func ExampleFindResources() {
+	ctx := context.Background()
+
+	// List every serial port, probing each one at two baud rates to see which
+	// instrument answers.
+	found, err := asrl.FindResources(ctx, "ASRL?*::INSTR",
+		asrl.WithProbe("*IDN?", 9600, 19200),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, r := range found {
+		fmt.Printf("%s (USB %t, serial %q): %s\n", r.Resource, r.IsUSB, r.SerialNumber, r.Response)
+	}
+}
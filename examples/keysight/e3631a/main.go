@@ -14,10 +14,8 @@ package main
 
 import (
 	"context"
-	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 
 	"github.com/gotmc/asrl"
@@ -66,7 +64,7 @@ func main() {
 
 	// Query the identification of the function generator.
 	idn, err := dev.Query(ctx, "*IDN?")
-	if err != nil && !errors.Is(err, io.EOF) {
+	if err != nil {
 		log.Fatalf("error querying serial port: %s", err)
 	}
 	log.Printf("query idn = %s", idn)
@@ -87,15 +85,15 @@ func main() {
 	}
 
 	// Query the voltage output
-	vc, err := dev.Query(ctx, "appl? p6v")
+	vc, err := dev.QueryFloat64s(ctx, "appl? p6v")
 	if err != nil {
 		log.Fatalf("error querying serial port: %s", err)
 	}
-	log.Printf("voltage, current = %s", vc)
+	log.Printf("voltage, current = %v", vc)
 
 	// Query the output state
-	state, err := dev.Query(ctx, "OUTP:STAT?")
-	if err != nil && !errors.Is(err, io.EOF) {
+	state, err := dev.QueryBool(ctx, "OUTP:STAT?")
+	if err != nil {
 		log.Fatalf("error querying serial port: %s", err)
 	}
 	log.Printf("output state = %v", state)
@@ -7,10 +7,8 @@ package main
 
 import (
 	"context"
-	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"time"
 
@@ -55,7 +53,7 @@ func main() {
 
 	// Query the identification of the function generator.
 	idn, err := dev.Query(ctx, "*idn?")
-	if err != nil && !errors.Is(err, io.EOF) {
+	if err != nil {
 		log.Fatalf("error querying serial port: %s", err)
 	}
 	log.Printf("query idn = %s", idn)
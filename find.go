@@ -0,0 +1,275 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.bug.st/serial"
+	"go.bug.st/serial/enumerator"
+)
+
+// ErrInvalidPattern is returned by FindResources when the VISA resource
+// expression cannot be parsed.
+var ErrInvalidPattern = errors.New("visa: invalid resource expression")
+
+// These are variables so that tests can replace the serial library.
+var (
+	openPort             = serial.Open
+	getPortsList         = serial.GetPortsList
+	getDetailedPortsList = enumerator.GetDetailedPortsList
+)
+
+// ResourceInfo describes a serial port found by FindResources.
+type ResourceInfo struct {
+	// Resource is the VISA resource string for the port, such as
+	// ASRL/dev/ttyUSB0::INSTR or, if an instrument answered the probe,
+	// ASRL/dev/ttyUSB0::19200::INSTR.
+	Resource string
+	// Address is the operating system's name for the serial port.
+	Address string
+	// IsUSB reports whether the port is a USB serial adapter, in which case
+	// VID, PID, SerialNumber, and Product are filled in where the operating
+	// system makes them available.
+	IsUSB        bool
+	VID          string
+	PID          string
+	SerialNumber string
+	Product      string
+	// Baud is the baud rate at which an instrument answered the probe, or zero
+	// if the port wasn't probed or nothing answered.
+	Baud int
+	// Response is the trimmed response to the probe, such as the *IDN?
+	// identification string.
+	Response string
+}
+
+// FindOption is a functional option for configuring FindResources.
+type FindOption func(*findConfig)
+
+type findConfig struct {
+	probe        string
+	bauds        []int
+	probeTimeout time.Duration
+	deviceOpts   []DeviceOption
+}
+
+// WithProbe sends the given query, such as *IDN?, to each port found at each
+// of the given baud rates in turn, and reports the first response in
+// ResourceInfo. If no baud rates are given, only 9600 is tried.
+func WithProbe(query string, bauds ...int) FindOption {
+	return func(c *findConfig) {
+		c.probe = query
+		c.bauds = bauds
+	}
+}
+
+// WithProbeTimeout sets how long to wait for a response to the probe at each
+// baud rate. The default is one second.
+func WithProbeTimeout(t time.Duration) FindOption {
+	return func(c *findConfig) {
+		c.probeTimeout = t
+	}
+}
+
+// WithProbeDeviceOptions sets the DeviceOption values used when opening each
+// port to probe it, such as WithWriteTermination and WithReadTermination for
+// instruments that use a carriage return.
+func WithProbeDeviceOptions(opts ...DeviceOption) FindOption {
+	return func(c *findConfig) {
+		c.deviceOpts = opts
+	}
+}
+
+// FindResources lists the serial ports whose VISA resource strings match the
+// given VISA resource expression, similar to viFindRsrc. In the expression, ?
+// matches any one character, [list] and [^list] match any one character in or
+// not in the list, * and + match zero or more and one or more repetitions of
+// the preceding character or group, | separates alternatives, parentheses
+// group, and \ escapes the following character. Matching is case insensitive.
+// For example, ASRL?*::INSTR matches every serial port, and an empty expression
+// does the same. Each port is matched using its resource string without a
+// baud rate, such as ASRL/dev/ttyUSB0::INSTR or, for Windows COM ports,
+// ASRL3::INSTR.
+//
+// If WithProbe is given, each matching port is opened and probed, which can
+// take several seconds per port. Ports that can't be opened or don't answer
+// are still listed, but without a response.
+func FindResources(
+	ctx context.Context,
+	pattern string,
+	opts ...FindOption,
+) ([]ResourceInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cfg := findConfig{probeTimeout: time.Second}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if len(cfg.bauds) == 0 {
+		cfg.bauds = []int{DefaultBaud}
+	}
+
+	if pattern == "" {
+		pattern = "?*"
+	}
+	re, err := visaPatternToRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := getPortsList()
+	if err != nil {
+		return nil, fmt.Errorf("listing serial ports: %w", err)
+	}
+	details := portDetails()
+
+	var found []ResourceInfo
+	for _, name := range names {
+		info := ResourceInfo{
+			Resource: resourceForPort(name, 0),
+			Address:  name,
+		}
+		if !re.MatchString(info.Resource) {
+			continue
+		}
+		if pd, ok := details[name]; ok {
+			info.IsUSB = pd.IsUSB
+			info.VID = pd.VID
+			info.PID = pd.PID
+			info.SerialNumber = pd.SerialNumber
+			info.Product = pd.Product
+		}
+		if cfg.probe != "" {
+			if err := probePort(ctx, &info, &cfg); err != nil {
+				return found, err
+			}
+		}
+		found = append(found, info)
+	}
+	return found, nil
+}
+
+// probePort queries the port at each candidate baud rate until an instrument
+// answers. Only a canceled parent context is reported as an error.
+func probePort(ctx context.Context, info *ResourceInfo, cfg *findConfig) error {
+	for _, baud := range cfg.bauds {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		address := fmt.Sprintf("ASRL::%s::%d::%s::INSTR", info.Address, baud, DefaultDataflow)
+		resp, err := probe(ctx, address, cfg.probe, cfg.probeTimeout, cfg.deviceOpts...)
+		if err != nil {
+			continue
+		}
+		info.Baud = baud
+		info.Resource = resourceForPort(info.Address, baud)
+		info.Response = resp
+		return nil
+	}
+	return nil
+}
+
+// probe opens the given address, sends the query, and returns the trimmed
+// response, which must be printable and end with the read termination.
+func probe(
+	ctx context.Context,
+	address, query string,
+	timeout time.Duration,
+	opts ...DeviceOption,
+) (string, error) {
+	opts = append([]DeviceOption{WithReadTimeout(timeout)}, opts...)
+	dev, err := NewDevice(ctx, address, opts...)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = dev.Close() }()
+	return dev.probe(ctx, query)
+}
+
+// portDetails returns the details of the serial ports, such as their USB
+// serial numbers, keyed by port name. Port details are only available on some
+// operating systems, so an error getting them isn't fatal and results in no
+// details.
+func portDetails() map[string]*enumerator.PortDetails {
+	details := map[string]*enumerator.PortDetails{}
+	if list, err := getDetailedPortsList(); err == nil {
+		for _, pd := range list {
+			if pd != nil && pd.Name != "" {
+				details[pd.Name] = pd
+			}
+		}
+	}
+	return details
+}
+
+// resourceForPort returns the canonical VISA resource string for a serial port
+// name, including the baud rate if it is nonzero.
+func resourceForPort(name string, baud int) string {
+	head := "ASRL" + name
+	if m := comPortRE.FindStringSubmatch(name); m != nil {
+		head = "ASRL" + m[1]
+	}
+	if baud > 0 {
+		return fmt.Sprintf("%s::%d::INSTR", head, baud)
+	}
+	return head + "::INSTR"
+}
+
+// visaPatternToRegexp converts a VISA resource expression into an anchored,
+// case-insensitive regular expression.
+func visaPatternToRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString(`(?i)^(?:`)
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '?':
+			b.WriteByte('.')
+		case '*', '+', '|', '(', ')':
+			b.WriteByte(c)
+		case '\\':
+			i++
+			if i == len(pattern) {
+				return nil, fmt.Errorf("%w %q: trailing backslash", ErrInvalidPattern, pattern)
+			}
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%w %q: unterminated list", ErrInvalidPattern, pattern)
+			}
+			list := pattern[i+1 : i+1+end]
+			b.WriteByte('[')
+			if strings.HasPrefix(list, "^") {
+				b.WriteByte('^')
+				list = list[1:]
+			}
+			for _, r := range list {
+				if r == '\\' || r == '[' || r == ']' {
+					b.WriteByte('\\')
+				}
+				b.WriteRune(r)
+			}
+			b.WriteByte(']')
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString(`)$`)
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrInvalidPattern, pattern, err)
+	}
+	return re, nil
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gotmc/asrl/asrltest"
+	"go.bug.st/serial"
+	"go.bug.st/serial/enumerator"
+)
+
+func TestVisaPatternToRegexp(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		pattern string
+		input   string
+		want    bool
+	}{
+		{pattern: "ASRL?*::INSTR", input: "ASRL/dev/ttyUSB0::INSTR", want: true},
+		{pattern: "ASRL?*::INSTR", input: "asrl3::instr", want: true},
+		{pattern: "ASRL?*::INSTR", input: "GPIB0::5::INSTR", want: false},
+		{pattern: "ASRL[0-9]::INSTR", input: "ASRL3::INSTR", want: true},
+		{pattern: "ASRL[0-9]::INSTR", input: "ASRL/dev/ttyS0::INSTR", want: false},
+		{pattern: "ASRL[^0-9]?*::INSTR", input: "ASRL/dev/ttyS0::INSTR", want: true},
+		{pattern: "ASRL(1|2)::INSTR", input: "ASRL2::INSTR", want: true},
+		{pattern: "ASRL1+::INSTR", input: "ASRL11::INSTR", want: true},
+		{pattern: "ASRL/dev/tty.usb?*::INSTR", input: "ASRL/dev/tty.usbserial::INSTR", want: true},
+		{pattern: "ASRL/dev/tty.usb?*::INSTR", input: "ASRL/dev/ttyXusbserial::INSTR", want: false},
+		{pattern: `ASRL\?::INSTR`, input: "ASRL?::INSTR", want: true},
+		{pattern: `ASRL\?::INSTR`, input: "ASRL1::INSTR", want: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.input, func(t *testing.T) {
+			t.Parallel()
+			re, err := visaPatternToRegexp(tc.pattern)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := re.MatchString(tc.input); got != tc.want {
+				t.Errorf("match = %t, want %t (regexp %s)", got, tc.want, re)
+			}
+		})
+	}
+}
+
+func TestVisaPatternToRegexpInvalid(t *testing.T) {
+	t.Parallel()
+	for _, pattern := range []string{`ASRL\`, "ASRL[0-9::INSTR", "ASRL(::INSTR"} {
+		if _, err := visaPatternToRegexp(pattern); !errors.Is(err, ErrInvalidPattern) {
+			t.Errorf("pattern %q: err = %v, want %v", pattern, err, ErrInvalidPattern)
+		}
+	}
+}
+
+func TestResourceForPort(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name string
+		baud int
+		want string
+	}{
+		{name: "/dev/ttyUSB0", want: "ASRL/dev/ttyUSB0::INSTR"},
+		{name: "/dev/ttyUSB0", baud: 19200, want: "ASRL/dev/ttyUSB0::19200::INSTR"},
+		{name: "COM3", want: "ASRL3::INSTR"},
+		{name: "COM3", baud: 9600, want: "ASRL3::9600::INSTR"},
+	} {
+		if got := resourceForPort(tc.name, tc.baud); got != tc.want {
+			t.Errorf("resourceForPort(%q, %d) = %q, want %q", tc.name, tc.baud, got, tc.want)
+		}
+		if _, err := NewVisaResource(tc.want); err != nil && !errors.Is(err, ErrUnknownBoard) {
+			t.Errorf("NewVisaResource(%q): %v", tc.want, err)
+		}
+	}
+}
+
+// fakeSerialLibrary replaces the serial port listing and opening functions for
+// the duration of a test. Tests using it must not run in parallel.
+func fakeSerialLibrary(
+	t *testing.T,
+	names []string,
+	details []*enumerator.PortDetails,
+	open func(string, *serial.Mode) (serial.Port, error),
+) {
+	t.Helper()
+	origOpen, origList, origDetails := openPort, getPortsList, getDetailedPortsList
+	t.Cleanup(func() {
+		openPort, getPortsList, getDetailedPortsList = origOpen, origList, origDetails
+	})
+	openPort = open
+	getPortsList = func() ([]string, error) { return names, nil }
+	getDetailedPortsList = func() ([]*enumerator.PortDetails, error) { return details, nil }
+}
+
+func TestFindResources(t *testing.T) {
+	fakeSerialLibrary(t,
+		[]string{"/dev/ttyS0", "/dev/ttyUSB0"},
+		[]*enumerator.PortDetails{
+			{Name: "/dev/ttyS0"},
+			{
+				Name:         "/dev/ttyUSB0",
+				IsUSB:        true,
+				VID:          "0403",
+				PID:          "6001",
+				SerialNumber: "PX484GRU",
+				Product:      "FT232R USB UART",
+			},
+		},
+		func(string, *serial.Mode) (serial.Port, error) {
+			return nil, errors.New("not probing")
+		},
+	)
+
+	found, err := FindResources(context.Background(), "ASRL/dev/ttyUSB?*::INSTR")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("found %d resources, want 1: %+v", len(found), found)
+	}
+	want := ResourceInfo{
+		Resource:     "ASRL/dev/ttyUSB0::INSTR",
+		Address:      "/dev/ttyUSB0",
+		IsUSB:        true,
+		VID:          "0403",
+		PID:          "6001",
+		SerialNumber: "PX484GRU",
+		Product:      "FT232R USB UART",
+	}
+	if found[0] != want {
+		t.Errorf("found %+v, want %+v", found[0], want)
+	}
+
+	all, err := FindResources(context.Background(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("found %d resources, want 2", len(all))
+	}
+}
+
+func TestFindResourcesProbe(t *testing.T) {
+	fakeSerialLibrary(t,
+		[]string{"/dev/ttyS0", "/dev/ttyUSB0"},
+		nil,
+		func(name string, mode *serial.Mode) (serial.Port, error) {
+			inst := asrltest.NewInstrument()
+			if name == "/dev/ttyUSB0" && mode.BaudRate == 19200 {
+				inst.Handle("*IDN?", "Agilent Technologies,34401A,0,11-5-2")
+			}
+			return inst, nil
+		},
+	)
+
+	found, err := FindResources(context.Background(), "ASRL?*::INSTR",
+		WithProbe("*IDN?", 9600, 19200),
+		WithProbeTimeout(20*time.Millisecond),
+		WithProbeDeviceOptions(WithDelayTime(0)),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("found %d resources, want 2", len(found))
+	}
+	if found[0].Baud != 0 || found[0].Response != "" {
+		t.Errorf("found[0] = %+v, want no probe response", found[0])
+	}
+	if found[1].Baud != 19200 {
+		t.Errorf("Baud = %d, want 19200", found[1].Baud)
+	}
+	if want := "Agilent Technologies,34401A,0,11-5-2"; found[1].Response != want {
+		t.Errorf("Response = %q, want %q", found[1].Response, want)
+	}
+	if want := "ASRL/dev/ttyUSB0::19200::INSTR"; found[1].Resource != want {
+		t.Errorf("Resource = %q, want %q", found[1].Resource, want)
+	}
+}
+
+func TestFindResourcesCanceled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := FindResources(ctx, ""); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
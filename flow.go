@@ -0,0 +1,282 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// Sentinel errors returned by flow control.
+var (
+	ErrCTSNotReady        = errors.New("asrl: CTS not ready")
+	ErrXONNotReceived     = errors.New("asrl: XON not received")
+	ErrInvalidFlowControl = errors.New("asrl: invalid flow control")
+)
+
+// Software flow control characters.
+const (
+	XON  byte = 0x11
+	XOFF byte = 0x13
+)
+
+// FlowControl is the flow control method used by a Device.
+type FlowControl int
+
+// Available flow control methods. The serial driver does not support flow
+// control itself, so every method is implemented by the Device: RTS/CTS and
+// DTR/DSR assert RTS or DTR and wait for the instrument to assert CTS or DSR
+// before sending a command, and XON/XOFF strips the XON and XOFF characters
+// from received data and pauses sending commands between an XOFF and the
+// following XON.
+const (
+	FlowNone FlowControl = iota
+	FlowRTSCTS
+	FlowDTRDSR
+	FlowXONXOFF
+)
+
+var flowControlNames = map[FlowControl]string{
+	FlowNone:    "NONE",
+	FlowRTSCTS:  "RTSCTS",
+	FlowDTRDSR:  "DTRDSR",
+	FlowXONXOFF: "XONXOFF",
+}
+
+// String returns the name of the flow control method as used in VISA resource
+// strings, such as "RTSCTS".
+func (f FlowControl) String() string {
+	if s, ok := flowControlNames[f]; ok {
+		return s
+	}
+	return fmt.Sprintf("FlowControl(%d)", int(f))
+}
+
+// ParseFlowControl parses the name of a flow control method. The name is case
+// insensitive and may separate the two signals with a slash, underscore, or
+// hyphen, so "RTSCTS", "rts/cts", and "RTS_CTS" are all accepted.
+func ParseFlowControl(s string) (FlowControl, error) {
+	name := strings.ToUpper(strings.NewReplacer("/", "", "_", "", "-", "").Replace(s))
+	for f, n := range flowControlNames {
+		if n == name {
+			return f, nil
+		}
+	}
+	return FlowNone, fmt.Errorf("%w %q", ErrInvalidFlowControl, s)
+}
+
+// WithFlowControl sets the flow control method. It overrides any flow control
+// given in the VISA resource string.
+func WithFlowControl(f FlowControl) DeviceOption {
+	return func(d *Device) {
+		d.flowControl = f
+	}
+}
+
+// FlowControl returns the flow control method.
+func (d *Device) FlowControl() FlowControl {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.flowControl
+}
+
+// SetFlowControl sets the flow control method, asserting the RTS or DTR line
+// as required.
+func (d *Device) SetFlowControl(f FlowControl) error {
+	if _, ok := flowControlNames[f]; !ok {
+		return fmt.Errorf("%w: %d", ErrInvalidFlowControl, int(f))
+	}
+	d.mu.Lock()
+	d.logger.Debug("flow control changed", "old", d.flowControl, "new", f)
+	d.flowControl = f
+	d.mu.Unlock()
+	return d.applyFlowControl()
+}
+
+// applyFlowControl configures the port for the current flow control method.
+func (d *Device) applyFlowControl() error {
+	flowControl := d.FlowControl()
+	d.xon.enabled.Store(flowControl == FlowXONXOFF)
+	switch flowControl {
+	case FlowRTSCTS:
+		if err := d.port.SetRTS(true); err != nil {
+			return fmt.Errorf("asserting RTS: %w", err)
+		}
+	case FlowDTRDSR:
+		if err := d.port.SetDTR(true); err != nil {
+			return fmt.Errorf("asserting DTR: %w", err)
+		}
+	}
+	return nil
+}
+
+// waitForFlowControl waits until the instrument is ready to receive data
+// according to the flow control method.
+func (d *Device) waitForFlowControl(ctx context.Context) error {
+	switch d.FlowControl() {
+	case FlowDTRDSR:
+		return d.napIfDataSetNotReady(ctx)
+	case FlowRTSCTS:
+		return d.napIfNotClearToSend(ctx)
+	case FlowXONXOFF:
+		start := time.Now()
+		if err := d.xon.waitForXON(ctx, d.ReadTimeout()); err != nil {
+			d.logger.DebugContext(ctx, "XON not received",
+				"elapsed", time.Since(start), "err", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func isCTS(port serial.Port) (bool, error) {
+	msb, err := port.GetModemStatusBits()
+	if err != nil {
+		return false, fmt.Errorf("getting modem status bits: %w", err)
+	}
+	return msb.CTS, nil
+}
+
+// xonxoffPort wraps a serial.Port to implement XON/XOFF software flow control.
+// When enabled, XON and XOFF characters are removed from the data returned by
+// Read and track whether the instrument has paused transmission.
+type xonxoffPort struct {
+	serial.Port
+	enabled atomic.Bool
+	mu      sync.Mutex
+	paused  bool
+	pending []byte
+}
+
+func (p *xonxoffPort) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	if len(p.pending) > 0 {
+		n := copy(b, p.pending)
+		p.pending = p.pending[n:]
+		p.mu.Unlock()
+		return n, nil
+	}
+	p.mu.Unlock()
+	if !p.enabled.Load() {
+		return p.Port.Read(b)
+	}
+
+	for {
+		n, err := p.Port.Read(b)
+		if n == 0 {
+			return 0, err
+		}
+		n = p.filter(b[:n])
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// ResetInputBuffer discards the data received by the port, including any kept
+// while waiting for XON.
+func (p *xonxoffPort) ResetInputBuffer() error {
+	p.mu.Lock()
+	p.pending = nil
+	p.mu.Unlock()
+	return p.Port.ResetInputBuffer()
+}
+
+// resume forgets any XOFF received, so that sending isn't paused.
+func (p *xonxoffPort) resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = false
+}
+
+// filter removes XON and XOFF characters from b in place, updating the paused
+// state, and returns the number of remaining bytes.
+func (p *xonxoffPort) filter(b []byte) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, c := range b {
+		switch c {
+		case XON:
+			p.paused = false
+		case XOFF:
+			p.paused = true
+		default:
+			b[n] = c
+			n++
+		}
+	}
+	return n
+}
+
+// waitForXON waits until the instrument has sent XON after an XOFF, reading
+// from the port to see it. Any input already received is read first, so that
+// an XOFF sent between transactions pauses the next write. Data received while
+// waiting is kept for the next Read.
+func (p *xonxoffPort) waitForXON(ctx context.Context, timeout time.Duration) error {
+	buf := make([]byte, 64)
+	if err := p.poll(buf); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		p.mu.Lock()
+		paused := p.paused
+		p.mu.Unlock()
+		if !paused {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w after %s", ErrXONNotReceived, timeout)
+		}
+		if _, err := p.keep(buf); err != nil {
+			return err
+		}
+	}
+}
+
+// poll reads the input already received by the port without waiting for more,
+// keeping it for the next Read.
+func (p *xonxoffPort) poll(buf []byte) (err error) {
+	if err := p.Port.SetReadTimeout(0); err != nil {
+		return fmt.Errorf("polling for XOFF: %w", err)
+	}
+	defer func() {
+		if rerr := p.Port.SetReadTimeout(pollInterval); err == nil && rerr != nil {
+			err = fmt.Errorf("polling for XOFF: %w", rerr)
+		}
+	}()
+	for {
+		n, err := p.keep(buf)
+		if n == 0 || err != nil {
+			return err
+		}
+	}
+}
+
+// keep reads from the port into buf, filtering XON and XOFF and appending the
+// remaining data to the data kept for the next Read. It returns the number of
+// bytes read from the port.
+func (p *xonxoffPort) keep(buf []byte) (int, error) {
+	n, err := p.Port.Read(buf)
+	if n > 0 {
+		m := p.filter(buf[:n])
+		p.mu.Lock()
+		p.pending = append(p.pending, buf[:m]...)
+		p.mu.Unlock()
+	}
+	return n, err
+}
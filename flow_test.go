@@ -0,0 +1,254 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gotmc/asrl/asrltest"
+)
+
+func TestParseFlowControl(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		input   string
+		want    FlowControl
+		wantErr error
+	}{
+		{input: "NONE", want: FlowNone},
+		{input: "RTSCTS", want: FlowRTSCTS},
+		{input: "rts/cts", want: FlowRTSCTS},
+		{input: "DTR_DSR", want: FlowDTRDSR},
+		{input: "xon-xoff", want: FlowXONXOFF},
+		{input: "", wantErr: ErrInvalidFlowControl},
+		{input: "RTS", wantErr: ErrInvalidFlowControl},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseFlowControl(tc.input)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("ParseFlowControl(%q) = %v, want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFlowControlString(t *testing.T) {
+	t.Parallel()
+	for fc, want := range map[FlowControl]string{
+		FlowNone:        "NONE",
+		FlowRTSCTS:      "RTSCTS",
+		FlowDTRDSR:      "DTRDSR",
+		FlowXONXOFF:     "XONXOFF",
+		FlowControl(42): "FlowControl(42)",
+	} {
+		if got := fc.String(); got != want {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestHWHandshakingFlowControl(t *testing.T) {
+	t.Parallel()
+	d := newTestDevice(newMockPort(""))
+	d.SetHWHandshaking(true)
+	if d.FlowControl() != FlowDTRDSR {
+		t.Errorf("FlowControl = %v, want %v", d.FlowControl(), FlowDTRDSR)
+	}
+	d.SetHWHandshaking(false)
+	if d.FlowControl() != FlowNone {
+		t.Errorf("FlowControl = %v, want %v", d.FlowControl(), FlowNone)
+	}
+	if err := d.SetFlowControl(FlowXONXOFF); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d.SetHWHandshaking(false)
+	if d.FlowControl() != FlowXONXOFF {
+		t.Errorf("FlowControl = %v, want %v", d.FlowControl(), FlowXONXOFF)
+	}
+	if err := d.SetFlowControl(FlowControl(42)); !errors.Is(err, ErrInvalidFlowControl) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidFlowControl)
+	}
+}
+
+func TestSetHWHandshakingAppliesFlowControl(t *testing.T) {
+	t.Parallel()
+	inst := asrltest.NewInstrument()
+	d, err := NewDeviceFromPort(inst, WithFlowControl(FlowXONXOFF))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d.SetHWHandshaking(true)
+	if !inst.DTR() {
+		t.Error("DTR not asserted")
+	}
+
+	// XON and XOFF are no longer stripped from received data.
+	inst.Send(string([]byte{'a', XON, XOFF, 'b'}))
+	buf := make([]byte, 8)
+	n, err := d.ReadBinary(context.Background(), buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "a\x11\x13b"; string(buf[:n]) != want {
+		t.Errorf("ReadBinary() = %q, want %q", buf[:n], want)
+	}
+}
+
+func TestCommandRTSCTS(t *testing.T) {
+	t.Parallel()
+	mp := newMockPort("")
+	mp.ctsReady = true
+	d := newTestDevice(mp)
+	d.flowControl = FlowRTSCTS
+	if err := d.Command(context.Background(), "*RST"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := mp.writeBuf.String(); got != "*RST\n" {
+		t.Errorf("written = %q, want %q", got, "*RST\n")
+	}
+}
+
+func TestCommandRTSCTSTimeout(t *testing.T) {
+	t.Parallel()
+	mp := newMockPort("")
+	mp.dsrReady = true
+	d := newTestDevice(mp)
+	d.flowControl = FlowRTSCTS
+	d.readTimeout = 5 * time.Millisecond
+	err := d.Command(context.Background(), "*RST")
+	if !errors.Is(err, ErrCTSNotReady) {
+		t.Fatalf("err = %v, want %v", err, ErrCTSNotReady)
+	}
+}
+
+func TestCommandRTSCTSNoDelay(t *testing.T) {
+	t.Parallel()
+	inst := asrltest.NewInstrument()
+	inst.SetCTS(false)
+	d, err := NewDeviceFromPort(inst,
+		WithFlowControl(FlowRTSCTS),
+		WithDelayTime(0),
+		WithReadTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.AfterFunc(5*time.Millisecond, func() { inst.SetCTS(true) })
+	if err := d.Command(context.Background(), "*RST"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestXONXOFF(t *testing.T) {
+	t.Parallel()
+	inst := asrltest.NewInstrument(asrltest.WithOutputTerminator(""))
+	inst.Handle("*IDN?", "AC\x11ME,1\n\x13")
+	d, err := NewDeviceFromPort(inst,
+		WithFlowControl(FlowXONXOFF),
+		WithDelayTime(1*time.Millisecond),
+		WithReadTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	// The response ends with XOFF, so the next command must wait for XON.
+	got, err := d.Query(ctx, "*IDN?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "ACME,1\n" {
+		t.Errorf("Query = %q, want %q", got, "ACME,1\n")
+	}
+
+	const pause = 20 * time.Millisecond
+	time.AfterFunc(pause, func() { inst.Send(string(XON)) })
+	start := time.Now()
+	if err := d.Command(ctx, "*RST"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < pause {
+		t.Errorf("Command returned after %v, want at least %v", elapsed, pause)
+	}
+}
+
+func TestXONXOFFBetweenTransactions(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]func(context.Context, *Device) error{
+		"command": func(ctx context.Context, d *Device) error {
+			return d.Command(ctx, "*RST")
+		},
+		"binary block": func(ctx context.Context, d *Device) error {
+			return d.CommandBinaryBlock(ctx, "DATA ", make([]byte, 2000))
+		},
+	}
+	for name, call := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			inst := asrltest.NewInstrument()
+			d, err := NewDeviceFromPort(inst,
+				WithFlowControl(FlowXONXOFF),
+				WithDelayTime(1*time.Millisecond),
+				WithReadTimeout(time.Second),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// The instrument sends XOFF while nothing is reading, so the next
+			// write must wait for XON.
+			inst.Send(string(XOFF))
+			const pause = 20 * time.Millisecond
+			time.AfterFunc(pause, func() { inst.Send(string(XON)) })
+			start := time.Now()
+			if err := call(context.Background(), d); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed < pause {
+				t.Errorf("returned after %v, want at least %v", elapsed, pause)
+			}
+			if got := len(inst.Commands()); got != 1 {
+				t.Errorf("len(Commands()) = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestXONXOFFTimeout(t *testing.T) {
+	t.Parallel()
+	inst := asrltest.NewInstrument()
+	inst.Send("OK\n" + string(XOFF))
+	d, err := NewDeviceFromPort(inst,
+		WithFlowControl(FlowXONXOFF),
+		WithDelayTime(1*time.Millisecond),
+		WithReadTimeout(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buf := make([]byte, 16)
+	n, err := d.Read(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := string(buf[:n]); got != "OK\n" {
+		t.Errorf("Read = %q, want %q", got, "OK\n")
+	}
+	err = d.Command(context.Background(), "*RST")
+	if !errors.Is(err, ErrXONNotReceived) {
+		t.Fatalf("err = %v, want %v", err, ErrXONNotReceived)
+	}
+}
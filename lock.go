@@ -0,0 +1,133 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrLocked is returned by Lock when the context already holds the Device's
+// exclusive lock.
+var ErrLocked = errors.New("asrl: device already locked")
+
+// ErrNotLocked is returned by Unlock when the context doesn't hold the
+// Device's exclusive lock.
+var ErrNotLocked = errors.New("asrl: device not locked by caller")
+
+// lockToken identifies a holder of the exclusive lock. It isn't zero sized, so
+// every token has a distinct address.
+type lockToken struct{ _ byte }
+
+// lockKey is the context key for the lock token of a Device.
+type lockKey struct{ d *Device }
+
+// Lock acquires an exclusive lock on the Device, similar to a VISA exclusive
+// lock, so that a sequence of transactions isn't interleaved with those of
+// other goroutines. Lock waits until any other holder calls Unlock, returning
+// the context error if the context is done first.
+//
+// Lock returns a context that carries the lock. The Device's methods must be
+// called with that context, or a context derived from it, to run while the
+// lock is held; called with any other context, they wait for Unlock like any
+// other goroutine. Goroutines sharing the lock context still run their
+// transactions one at a time. Call Unlock with the lock context when the
+// sequence is complete:
+//
+//	lctx, err := dev.Lock(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	defer dev.Unlock(lctx)
+//	err = dev.Command(lctx, "TRIG:SOUR BUS")
+func (d *Device) Lock(ctx context.Context) (context.Context, error) {
+	if d.holds(ctx) {
+		return ctx, ErrLocked
+	}
+	if err := ctx.Err(); err != nil {
+		return ctx, err
+	}
+	select {
+	case d.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx, fmt.Errorf("waiting for lock: %w", ctx.Err())
+	}
+	tok := &lockToken{}
+	d.mu.Lock()
+	d.holder = tok
+	d.mu.Unlock()
+	d.logger.DebugContext(ctx, "device locked")
+	return context.WithValue(ctx, lockKey{d}, tok), nil
+}
+
+// Unlock releases the exclusive lock acquired by Lock. The context must be the
+// one returned by Lock, or one derived from it; otherwise Unlock returns
+// ErrNotLocked and the lock remains held.
+func (d *Device) Unlock(lctx context.Context) error {
+	tok, _ := lctx.Value(lockKey{d}).(*lockToken)
+	d.mu.Lock()
+	if tok == nil || d.holder != tok {
+		d.mu.Unlock()
+		return ErrNotLocked
+	}
+	d.holder = nil
+	d.mu.Unlock()
+	<-d.sem
+	d.logger.DebugContext(lctx, "device unlocked")
+	return nil
+}
+
+// holds reports whether ctx carries the Device's current exclusive lock.
+func (d *Device) holds(ctx context.Context) bool {
+	tok, ok := ctx.Value(lockKey{d}).(*lockToken)
+	if !ok {
+		return false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.holder == tok
+}
+
+// acquire waits for exclusive use of the Device for a single transaction,
+// returning a function that releases it. If ctx carries the exclusive lock
+// from Lock, acquire doesn't wait for Unlock, but still waits for any other
+// transaction using the lock context to finish. Before acquire returns, a lost
+// port is reopened, if WithReconnect is enabled, and any input left unread by
+// an earlier failed transaction is discarded.
+func (d *Device) acquire(ctx context.Context) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	unlock := func() {}
+	if !d.holds(ctx) {
+		select {
+		case d.sem <- struct{}{}:
+			unlock = func() { <-d.sem }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	select {
+	case d.tx <- struct{}{}:
+	case <-ctx.Done():
+		unlock()
+		return nil, ctx.Err()
+	}
+	release := func() {
+		<-d.tx
+		unlock()
+	}
+	if err := d.reopen(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	if err := d.resync(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
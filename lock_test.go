@@ -0,0 +1,229 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gotmc/asrl/asrltest"
+)
+
+func newEchoDevice(t *testing.T) (*Device, *asrltest.Instrument) {
+	t.Helper()
+	inst := asrltest.NewInstrument()
+	inst.HandleRegexpFunc(`^ECHO\? (.*)$`, func(m []string) string { return m[1] })
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(0),
+		WithReadTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return d, inst
+}
+
+func TestConcurrentQueries(t *testing.T) {
+	t.Parallel()
+
+	d, _ := newEchoDevice(t)
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			want := fmt.Sprintf("goroutine %d", i)
+			for range 5 {
+				got, err := d.Query(ctx, "ECHO? "+want)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+				if got != want+"\n" {
+					t.Errorf("Query() = %q, want %q", got, want+"\n")
+					return
+				}
+			}
+		}()
+	}
+	// Change settings while queries are in flight.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 20 {
+			d.SetDelayTime(d.DelayTime())
+			d.SetReadTimeout(d.ReadTimeout())
+			d.SetWriteTermination(d.WriteTermination())
+			d.SetErrorChecking(d.ErrorChecking())
+			_ = d.SetFlowControl(d.FlowControl())
+		}
+	}()
+	wg.Wait()
+}
+
+func TestLock(t *testing.T) {
+	t.Parallel()
+
+	d, inst := newEchoDevice(t)
+	ctx := context.Background()
+	lctx, err := d.Lock(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Other goroutines wait for Unlock.
+	done := make(chan error, 1)
+	go func() {
+		_, err := d.Query(ctx, "ECHO? other")
+		done <- err
+	}()
+	shortCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := d.Query(shortCtx, "ECHO? blocked"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The holder's transactions run while the lock is held.
+	if err := d.Command(lctx, "FIRST"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, err := d.Query(lctx, "ECHO? second"); err != nil || got != "second\n" {
+		t.Fatalf("Query() = %q, %v, want %q", got, err, "second\n")
+	}
+	if _, err := d.Lock(lctx); !errors.Is(err, ErrLocked) {
+		t.Errorf("err = %v, want %v", err, ErrLocked)
+	}
+	if err := d.Unlock(lctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"FIRST", "ECHO? second", "ECHO? other"}
+	got := inst.Commands()
+	if len(got) != len(want) {
+		t.Fatalf("Commands() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Commands() = %q, want %q", got, want)
+			break
+		}
+	}
+}
+
+func TestLockStaleContext(t *testing.T) {
+	t.Parallel()
+
+	d, _ := newEchoDevice(t)
+	ctx := context.Background()
+	stale, err := d.Lock(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.Unlock(stale); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lctx, err := d.Lock(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer d.Unlock(lctx)
+	if err := d.Unlock(stale); !errors.Is(err, ErrNotLocked) {
+		t.Errorf("err = %v, want %v", err, ErrNotLocked)
+	}
+	stale, cancel := context.WithTimeout(stale, 20*time.Millisecond)
+	defer cancel()
+	if err := d.Command(stale, "CMD"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestLockTimeout(t *testing.T) {
+	t.Parallel()
+
+	d, _ := newEchoDevice(t)
+	lctx, err := d.Lock(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer d.Unlock(lctx)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := d.Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestUnlockNotHolder(t *testing.T) {
+	t.Parallel()
+
+	d, _ := newEchoDevice(t)
+	ctx := context.Background()
+	if err := d.Unlock(ctx); !errors.Is(err, ErrNotLocked) {
+		t.Errorf("err = %v, want %v", err, ErrNotLocked)
+	}
+	lctx, err := d.Lock(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer d.Unlock(lctx)
+	if err := d.Unlock(ctx); !errors.Is(err, ErrNotLocked) {
+		t.Errorf("err = %v, want %v", err, ErrNotLocked)
+	}
+
+	// The lock is still held.
+	shortCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := d.Lock(shortCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestLockSharedContext(t *testing.T) {
+	t.Parallel()
+
+	d, _ := newEchoDevice(t)
+	// A delay after each command makes the transactions overlap if they aren't
+	// run one at a time.
+	d.SetDelayTime(time.Millisecond)
+	lctx, err := d.Lock(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer d.Unlock(lctx)
+
+	// Goroutines sharing the lock context run their transactions one at a time.
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithCancel(lctx)
+			defer cancel()
+			want := fmt.Sprintf("goroutine %d", i)
+			for range 5 {
+				got, err := d.Query(ctx, "ECHO? "+want)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+				if got != want+"\n" {
+					t.Errorf("Query() = %q, want %q", got, want+"\n")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
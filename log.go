@@ -0,0 +1,26 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import "log/slog"
+
+// LevelTrace is the log level used for the most verbose records, such as each
+// Data Set Ready poll and each raw binary read and write. It is below
+// slog.LevelDebug, so a handler must be configured with a level of LevelTrace
+// or lower to emit these records.
+const LevelTrace = slog.LevelDebug - 4
+
+// WithLogger sets the logger used to report commands, query responses, Data
+// Set Ready polling, read timeout changes, and closing the Device. Records are
+// logged at slog.LevelDebug or LevelTrace and include the VISA resource string
+// when the Device was opened with NewDevice. By default, nothing is logged.
+func WithLogger(logger *slog.Logger) DeviceOption {
+	return func(d *Device) {
+		if logger != nil {
+			d.logger = logger
+		}
+	}
+}
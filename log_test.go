@@ -0,0 +1,70 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithLogger(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: LevelTrace}))
+	mp := newMockPort("ACME,1\n")
+	mp.dsrReady = true
+	d, err := NewDeviceFromPort(mp,
+		WithLogger(logger),
+		WithHWHandshaking(true),
+		WithDelayTime(1*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := d.Query(context.Background(), "*IDN?"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d.SetReadTimeout(time.Second)
+	if err := d.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`msg="device opened"`,
+		`msg="DSR poll" ready=true`,
+		`msg="DSR ready"`,
+		`msg=command cmd=*IDN?`,
+		`msg=query cmd=*IDN? response="ACME,1\n"`,
+		`msg="read timeout changed" old=5s new=1s`,
+		`msg="device closed"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output missing %s\noutput:\n%s", want, out)
+		}
+	}
+}
+
+func TestWithLoggerLevelFiltering(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	mp := newMockPort("")
+	d, err := NewDeviceFromPort(mp, WithLogger(logger))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := d.WriteBinary(context.Background(), []byte("data")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(buf.String(), "binary write") {
+		t.Errorf("trace record logged at debug level:\n%s", buf.String())
+	}
+}
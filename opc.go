@@ -0,0 +1,79 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// errNotComplete is wrapped by the ResponseError returned by WaitForOPC when
+// the response to *OPC? isn't 1.
+var errNotComplete = errors.New("operation complete response was not 1")
+
+// DefaultOPCTimeout is how long WaitForOPC waits for the response to *OPC? if
+// the context has no deadline.
+const DefaultOPCTimeout = 60 * time.Second
+
+// WaitForOPC waits until the instrument has completed all pending operations,
+// such as output settling or a sweep, by sending *OPC? and waiting for the
+// instrument to answer 1. Since the answer only comes once the operations are
+// complete, the read timeout is extended for this call to the context's
+// deadline or, if the context has no deadline, to DefaultOPCTimeout.
+func (d *Device) WaitForOPC(ctx context.Context) error {
+	release, err := d.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	timeout := DefaultOPCTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	start := time.Now()
+	resp, err := d.query(ctx, "*OPC?", timeout)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(resp) != "1" {
+		return &ResponseError{Command: "*OPC?", Response: resp, Err: errNotComplete}
+	}
+	d.logger.DebugContext(ctx, "operation complete", "elapsed", time.Since(start))
+	return nil
+}
+
+// PollUntil sends the query every interval until the predicate returns true
+// for the response, with surrounding whitespace trimmed, and returns that
+// response. This is useful for waiting on a status register, such as *ESR? or
+// STAT:OPER:COND?, since a serial port has no service request line. If a query
+// fails or the context is done first, PollUntil returns the last response
+// received along with the error.
+func (d *Device) PollUntil(
+	ctx context.Context,
+	query string,
+	predicate func(resp string) bool,
+	interval time.Duration,
+) (string, error) {
+	start := time.Now()
+	var last string
+	for polls := 1; ; polls++ {
+		resp, err := d.Query(ctx, query)
+		if err != nil {
+			return last, err
+		}
+		last = strings.TrimSpace(resp)
+		if predicate(last) {
+			d.logger.DebugContext(ctx, "poll condition met", "query", query,
+				"response", last, "polls", polls, "elapsed", time.Since(start))
+			return last, nil
+		}
+		if err := sleepContext(ctx, interval); err != nil {
+			return last, err
+		}
+	}
+}
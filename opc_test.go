@@ -0,0 +1,108 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gotmc/asrl/asrltest"
+)
+
+func TestWaitForOPC(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument(asrltest.WithResponseDelay(100 * time.Millisecond))
+	inst.Handle("*OPC?", "1")
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := d.WaitForOPC(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := inst.Commands(); len(got) != 1 || got[0] != "*OPC?" {
+		t.Errorf("Commands() = %q, want [*OPC?]", got)
+	}
+}
+
+func TestWaitForOPCInvalidResponse(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDevice(newMockPort("0\n"))
+	err := d.WaitForOPC(context.Background())
+	var re *ResponseError
+	if !errors.As(err, &re) {
+		t.Errorf("err = %v, want *ResponseError", err)
+	}
+}
+
+func TestPollUntil(t *testing.T) {
+	t.Parallel()
+
+	var polls atomic.Int32
+	inst := asrltest.NewInstrument()
+	inst.HandleFunc("*ESR?", func(_ []string) string {
+		if polls.Add(1) < 3 {
+			return "+0"
+		}
+		return "+1"
+	})
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opc := func(resp string) bool {
+		esr, err := strconv.Atoi(resp)
+		return err == nil && esr&1 != 0
+	}
+	got, err := d.PollUntil(context.Background(), "*ESR?", opc, time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "+1" {
+		t.Errorf("PollUntil() = %q, want %q", got, "+1")
+	}
+	if n := polls.Load(); n != 3 {
+		t.Errorf("polled %d times, want 3", n)
+	}
+}
+
+func TestPollUntilDeadline(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	inst.Handle("STAT:OPER:COND?", "+0")
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	never := func(string) bool { return false }
+	got, err := d.PollUntil(ctx, "STAT:OPER:COND?", never, 5*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got != "+0" {
+		t.Errorf("PollUntil() = %q, want last response %q", got, "+0")
+	}
+}
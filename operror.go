@@ -0,0 +1,78 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OpError is the error returned when opening a Device or communicating with
+// it fails, describing the failed operation. The underlying error, such as
+// ErrDSRNotReady or a *TimeoutError, can be checked using errors.Is and
+// errors.As.
+type OpError struct {
+	// Op is the operation that failed: "open", "handshake", "write", "read",
+	// "discard", "clear", or "configure". A handshake is the wait for flow
+	// control before sending a command, discard is the discarding of input
+	// left by a failed transaction, and configure is changing the serial
+	// settings.
+	Op string
+	// Resource is the VISA resource string of the Device, or empty if the
+	// Device was created from an already-open port.
+	Resource string
+	// Command is the command being sent or whose response was being read, or
+	// empty if unknown, such as for ReadBinary.
+	Command string
+	// N is the number of bytes written or read before the error.
+	N int
+	// Elapsed is how long the operation ran before it failed.
+	Elapsed time.Duration
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *OpError) Error() string {
+	var b strings.Builder
+	b.WriteString("asrl: " + e.Op)
+	if e.Command != "" {
+		fmt.Fprintf(&b, " %q", e.Command)
+	}
+	if e.Resource != "" {
+		b.WriteString(" on " + e.Resource)
+	}
+	b.WriteString(": " + e.Err.Error())
+	return b.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *OpError) Unwrap() error { return e.Err }
+
+// opError returns err wrapped in an *OpError for the Device, or nil if err is
+// nil. An error that is already an *OpError is returned as is.
+func (d *Device) opError(op, cmd string, n int, start time.Time, err error) error {
+	if err == nil {
+		return nil
+	}
+	var opErr *OpError
+	if errors.As(err, &opErr) {
+		return err
+	}
+	var resource string
+	if d.resource != nil {
+		resource = d.resource.String()
+	}
+	return &OpError{
+		Op:       op,
+		Resource: resource,
+		Command:  strings.TrimSpace(cmd),
+		N:        n,
+		Elapsed:  time.Since(start),
+		Err:      err,
+	}
+}
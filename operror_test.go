@@ -0,0 +1,221 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gotmc/asrl/asrltest"
+	"go.bug.st/serial"
+)
+
+func TestOpError(t *testing.T) {
+	t.Parallel()
+
+	errWrite := errors.New("write failed")
+	testCases := map[string]struct {
+		setup   func(*asrltest.Instrument)
+		opts    []DeviceOption
+		call    func(*Device) error
+		wantOp  string
+		wantCmd string
+		wantN   int
+		wantErr error
+	}{
+		"read timeout": {
+			setup: func(inst *asrltest.Instrument) {
+				inst.HandleFunc("*IDN?", func([]string) string {
+					inst.Send("ACME")
+					return ""
+				})
+			},
+			call: func(d *Device) error {
+				_, err := d.Query(context.Background(), "*IDN?")
+				return err
+			},
+			wantOp:  "read",
+			wantCmd: "*IDN?",
+			wantN:   4,
+			wantErr: ErrTimeout,
+		},
+		"handshake": {
+			setup: func(inst *asrltest.Instrument) { inst.SetDSR(false) },
+			opts:  []DeviceOption{WithFlowControl(FlowDTRDSR)},
+			call: func(d *Device) error {
+				return d.Command(context.Background(), "VOLT %d", 5)
+			},
+			wantOp:  "handshake",
+			wantCmd: "VOLT 5",
+			wantErr: ErrDSRNotReady,
+		},
+		"write": {
+			setup: func(inst *asrltest.Instrument) { inst.InjectWriteError(errWrite) },
+			call: func(d *Device) error {
+				return d.Command(context.Background(), "OUTP ON")
+			},
+			wantOp:  "write",
+			wantCmd: "OUTP ON",
+			wantErr: errWrite,
+		},
+		"binary read": {
+			call: func(d *Device) error {
+				_, err := d.ReadBinary(context.Background(), make([]byte, 8))
+				return err
+			},
+			wantOp:  "read",
+			wantErr: ErrTimeout,
+		},
+		"clear": {
+			setup: func(inst *asrltest.Instrument) { inst.InjectWriteError(errWrite) },
+			opts:  []DeviceOption{WithClearString("*CLS")},
+			call: func(d *Device) error {
+				return d.Clear(context.Background())
+			},
+			wantOp:  "clear",
+			wantCmd: "*CLS",
+			wantErr: errWrite,
+		},
+		"discard": {
+			call: func(d *Device) error {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+				_, _ = d.Query(ctx, "FIRST?")
+				// The next transaction waits for the rest of the response.
+				ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+				_, err := d.Query(ctx, "SECOND?")
+				return err
+			},
+			wantOp:  "discard",
+			wantErr: context.DeadlineExceeded,
+		},
+		"block read": {
+			setup: func(inst *asrltest.Instrument) { inst.Handle("CURV?", "#15AB") },
+			call: func(d *Device) error {
+				_, err := d.QueryBinaryBlock(context.Background(), "CURV?")
+				return err
+			},
+			wantOp:  "read",
+			wantCmd: "CURV?",
+			wantN:   3,
+			wantErr: ErrTimeout,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			inst := asrltest.NewInstrument()
+			if tc.setup != nil {
+				tc.setup(inst)
+			}
+			opts := append([]DeviceOption{
+				WithDelayTime(time.Millisecond),
+				WithReadTimeout(30 * time.Millisecond),
+			}, tc.opts...)
+			d, err := NewDeviceFromPort(inst, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			err = tc.call(d)
+			var opErr *OpError
+			if !errors.As(err, &opErr) {
+				t.Fatalf("err = %v, want *OpError", err)
+			}
+			if opErr.Op != tc.wantOp {
+				t.Errorf("Op = %q, want %q", opErr.Op, tc.wantOp)
+			}
+			if opErr.Command != tc.wantCmd {
+				t.Errorf("Command = %q, want %q", opErr.Command, tc.wantCmd)
+			}
+			if opErr.N != tc.wantN {
+				t.Errorf("N = %d, want %d", opErr.N, tc.wantN)
+			}
+			if opErr.Resource != "" {
+				t.Errorf("Resource = %q, want empty", opErr.Resource)
+			}
+			if opErr.Elapsed <= 0 {
+				t.Errorf("Elapsed = %s, want positive", opErr.Elapsed)
+			}
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("err = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpErrorOpen(t *testing.T) {
+	errOpen := errors.New("no such file or directory")
+	fakeSerialLibrary(t, nil, nil, func(string, *serial.Mode) (serial.Port, error) {
+		return nil, errOpen
+	})
+	const address = "ASRL::/dev/ttyUSB9::9600::8N1::INSTR"
+	_, err := NewDevice(context.Background(), address)
+	var opErr *OpError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("err = %v, want *OpError", err)
+	}
+	if opErr.Op != "open" || opErr.Resource != address {
+		t.Errorf("OpError = %+v, want open of %s", opErr, address)
+	}
+	if !errors.Is(err, errOpen) {
+		t.Errorf("err = %v, want %v", err, errOpen)
+	}
+}
+
+func TestOpErrorResource(t *testing.T) {
+	ports := newFakeUSBPorts(t)
+	inst := asrltest.NewInstrument()
+	ports.plug("/dev/ttyUSB0", "FT1234", inst)
+	const address = "ASRL::/dev/ttyUSB0::9600::8N1::INSTR"
+	d, err := NewDevice(context.Background(), address,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer d.Close()
+	_, err = d.Query(context.Background(), "*IDN?")
+	var opErr *OpError
+	if !errors.As(err, &opErr) || opErr.Resource != address {
+		t.Errorf("err = %v, want *OpError for %s", err, address)
+	}
+}
+
+func TestOpErrorString(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		err  *OpError
+		want string
+	}{
+		{
+			err:  &OpError{Op: "open", Resource: "COM3", Err: errors.New("access denied")},
+			want: "asrl: open on COM3: access denied",
+		},
+		{
+			err: &OpError{
+				Op:       "read",
+				Resource: "ASRL1::INSTR",
+				Command:  "*IDN?",
+				Err:      &TimeoutError{ReadTimeout: 5 * time.Second},
+			},
+			want: `asrl: read "*IDN?" on ASRL1::INSTR: asrl: read timeout after 5s`,
+		},
+		{
+			err:  &OpError{Op: "write", Err: errors.New("broken pipe")},
+			want: "asrl: write: broken pipe",
+		},
+	}
+	for _, tc := range testCases {
+		if got := tc.err.Error(); got != tc.want {
+			t.Errorf("Error() = %q, want %q", got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// errInvalidBool is wrapped by the ResponseError returned by QueryBool when
+// the response isn't 0, 1, ON, or OFF.
+var errInvalidBool = errors.New("invalid boolean")
+
+// ResponseError is returned by the typed query helpers, such as QueryFloat64,
+// when the response can't be parsed.
+type ResponseError struct {
+	Command  string
+	Response string
+	Err      error
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("asrl: parsing response %q to %q: %v", e.Response, e.Command, e.Err)
+}
+
+func (e *ResponseError) Unwrap() error { return e.Err }
+
+// QueryString sends the query and returns the response with surrounding
+// whitespace and any enclosing double or single quotes removed. Doubled quotes
+// within a quoted response, which SCPI uses to escape a quote, are undoubled.
+func (d *Device) QueryString(ctx context.Context, cmd string) (string, error) {
+	s, err := d.Query(ctx, cmd)
+	if err != nil {
+		return "", err
+	}
+	return unquote(strings.TrimSpace(s)), nil
+}
+
+// QueryFloat64 sends the query and parses the response as a floating point
+// number, such as 1.234E+00.
+func (d *Device) QueryFloat64(ctx context.Context, cmd string) (float64, error) {
+	return queryParse(ctx, d, cmd, parseFloat64)
+}
+
+// QueryInt sends the query and parses the response as a decimal integer, such
+// as +15.
+func (d *Device) QueryInt(ctx context.Context, cmd string) (int, error) {
+	return queryParse(ctx, d, cmd, strconv.Atoi)
+}
+
+// QueryBool sends the query and parses the response as a boolean, accepting 0,
+// 1, ON, and OFF in any case.
+func (d *Device) QueryBool(ctx context.Context, cmd string) (bool, error) {
+	return queryParse(ctx, d, cmd, parseBool)
+}
+
+// QueryFloat64s sends the query and parses the response as a comma-separated
+// list of floating point numbers, such as the quoted response to APPL? P6V. The
+// list may be enclosed in quotes, and an empty response returns an empty list.
+func (d *Device) QueryFloat64s(ctx context.Context, cmd string) ([]float64, error) {
+	return queryParse(ctx, d, cmd, parseList(parseFloat64))
+}
+
+// QueryInts sends the query and parses the response as a comma-separated list
+// of decimal integers. The list may be enclosed in quotes, and an empty
+// response returns an empty list.
+func (d *Device) QueryInts(ctx context.Context, cmd string) ([]int, error) {
+	return queryParse(ctx, d, cmd, parseList(strconv.Atoi))
+}
+
+// queryParse sends the query and parses the trimmed response, returning a
+// ResponseError if it can't be parsed.
+func queryParse[T any](
+	ctx context.Context,
+	d *Device,
+	cmd string,
+	parse func(string) (T, error),
+) (T, error) {
+	var zero T
+	s, err := d.Query(ctx, cmd)
+	if err != nil {
+		return zero, err
+	}
+	v, err := parse(strings.TrimSpace(s))
+	if err != nil {
+		return zero, &ResponseError{Command: strings.TrimSpace(cmd), Response: s, Err: err}
+	}
+	return v, nil
+}
+
+// parseList returns a parser for a comma-separated list of values, which may
+// be enclosed in quotes.
+func parseList[T any](parse func(string) (T, error)) func(string) ([]T, error) {
+	return func(s string) ([]T, error) {
+		s = strings.TrimSpace(unquote(s))
+		if s == "" {
+			return []T{}, nil
+		}
+		fields := strings.Split(s, ",")
+		values := make([]T, len(fields))
+		for i, f := range fields {
+			v, err := parse(strings.TrimSpace(f))
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			values[i] = v
+		}
+		return values, nil
+	}
+}
+
+func parseFloat64(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+func parseBool(s string) (bool, error) {
+	switch strings.ToUpper(s) {
+	case "1", "ON":
+		return true, nil
+	case "0", "OFF":
+		return false, nil
+	}
+	return false, fmt.Errorf("%w %q", errInvalidBool, s)
+}
+
+// unquote removes enclosing double or single quotes from s, undoubling any
+// doubled quotes within.
+func unquote(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	q := s[0]
+	if (q != '"' && q != '\'') || s[len(s)-1] != q {
+		return s
+	}
+	return strings.ReplaceAll(s[1:len(s)-1], string(q)+string(q), string(q))
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestQueryString(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		response string
+		want     string
+	}{
+		{response: "  ACME,1 \r\n", want: "ACME,1"},
+		{response: "\"HELLO\"\n", want: "HELLO"},
+		{response: "'HELLO'\n", want: "HELLO"},
+		{response: "\"say \"\"hi\"\"\"\n", want: "say \"hi\""},
+		{response: "\"\n", want: "\""},
+		{response: "\"A'\n", want: "\"A'"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.response, func(t *testing.T) {
+			t.Parallel()
+			d := newTestDevice(newMockPort(tc.response))
+			got, err := d.QueryString(context.Background(), "SYST:ERR?")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("QueryString() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestQueryFloat64(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDevice(newMockPort("+1.234E+00\n"))
+	got, err := d.QueryFloat64(context.Background(), "VOLT?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != 1.234 {
+		t.Errorf("QueryFloat64() = %v, want %v", got, 1.234)
+	}
+
+	d = newTestDevice(newMockPort("OVLD\n"))
+	_, err = d.QueryFloat64(context.Background(), " VOLT? ")
+	var re *ResponseError
+	if !errors.As(err, &re) {
+		t.Fatalf("err = %v, want *ResponseError", err)
+	}
+	if re.Command != "VOLT?" || re.Response != "OVLD\n" {
+		t.Errorf("ResponseError = %+v, want command %q and response %q", re, "VOLT?", "OVLD\n")
+	}
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("err = %v, want %v", err, strconv.ErrSyntax)
+	}
+}
+
+func TestQueryInt(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDevice(newMockPort("+15\n"))
+	got, err := d.QueryInt(context.Background(), "*ESR?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != 15 {
+		t.Errorf("QueryInt() = %d, want %d", got, 15)
+	}
+
+	d = newTestDevice(newMockPort("1.5\n"))
+	_, err = d.QueryInt(context.Background(), "*ESR?")
+	var re *ResponseError
+	if !errors.As(err, &re) {
+		t.Errorf("err = %v, want *ResponseError", err)
+	}
+}
+
+func TestQueryBool(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		response string
+		want     bool
+		wantErr  bool
+	}{
+		{response: "1\n", want: true},
+		{response: "0\n", want: false},
+		{response: "ON\n", want: true},
+		{response: "off\n", want: false},
+		{response: "2\n", wantErr: true},
+		{response: "\n", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.response, func(t *testing.T) {
+			t.Parallel()
+			d := newTestDevice(newMockPort(tc.response))
+			got, err := d.QueryBool(context.Background(), "OUTP?")
+			var re *ResponseError
+			if tc.wantErr != errors.As(err, &re) {
+				t.Fatalf("err = %v, want ResponseError %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("QueryBool() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestQueryFloat64s(t *testing.T) {
+	t.Parallel()
+
+	for _, response := range []string{
+		"\"+6.000000E+00,+1.000000E+00\"\n",
+		"+6.000000E+00, +1.000000E+00\n",
+	} {
+		d := newTestDevice(newMockPort(response))
+		got, err := d.QueryFloat64s(context.Background(), "APPL? P6V")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := []float64{6, 1}; !slices.Equal(got, want) {
+			t.Errorf("QueryFloat64s() = %v, want %v", got, want)
+		}
+	}
+
+	d := newTestDevice(newMockPort("6,ABC\n"))
+	_, err := d.QueryFloat64s(context.Background(), "APPL? P6V")
+	var re *ResponseError
+	if !errors.As(err, &re) {
+		t.Errorf("err = %v, want *ResponseError", err)
+	}
+
+	d = newTestDevice(newMockPort("\n"))
+	got, err := d.QueryFloat64s(context.Background(), "DATA?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("QueryFloat64s() = %v, want empty", got)
+	}
+}
+
+func TestQueryInts(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDevice(newMockPort("1,+2,-3\n"))
+	got, err := d.QueryInts(context.Background(), "DATA?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []int{1, 2, -3}; !slices.Equal(got, want) {
+		t.Errorf("QueryInts() = %v, want %v", got, want)
+	}
+
+	d = newTestDevice(newMockPort("1,,3\n"))
+	_, err = d.QueryInts(context.Background(), "DATA?")
+	var re *ResponseError
+	if !errors.As(err, &re) {
+		t.Errorf("err = %v, want *ResponseError", err)
+	}
+}
+
+func TestQueryHelperTransportError(t *testing.T) {
+	t.Parallel()
+
+	mp := newMockPort("")
+	mp.writeErr = errors.New("write failed")
+	d := newTestDevice(mp)
+	_, err := d.QueryFloat64(context.Background(), "VOLT?")
+	var re *ResponseError
+	if err == nil || errors.As(err, &re) {
+		t.Errorf("err = %v, want transport error", err)
+	}
+}
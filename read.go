@@ -0,0 +1,188 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// ErrTimeout is returned, wrapped in a *TimeoutError, when the read timeout
+// elapses without any data arriving. It satisfies
+// errors.Is(err, os.ErrDeadlineExceeded), and like net.Error, it has a Timeout
+// method that returns true.
+var ErrTimeout error = timeoutError{}
+
+// timeoutError is the type of ErrTimeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "asrl: read timeout" }
+
+// Is reports whether target is os.ErrDeadlineExceeded, so that ErrTimeout can
+// be checked in the same way as other I/O timeouts.
+func (timeoutError) Is(target error) bool { return target == os.ErrDeadlineExceeded }
+
+// Timeout returns true.
+func (timeoutError) Timeout() bool { return true }
+
+// Temporary returns true, since a later read may succeed.
+func (timeoutError) Temporary() bool { return true }
+
+// TimeoutError is returned when the read timeout elapses before a complete
+// response arrives. It wraps ErrTimeout and holds any partial data received,
+// so that a silent instrument can be told apart from one that stopped part way
+// through a response, or from a closed port.
+type TimeoutError struct {
+	// Partial is the data received before the timeout, if any.
+	Partial []byte
+	// ReadTimeout is how long the read waited for more data.
+	ReadTimeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	if len(e.Partial) == 0 {
+		return fmt.Sprintf("asrl: read timeout after %s", e.ReadTimeout)
+	}
+	return fmt.Sprintf("asrl: read timeout after %s with %d bytes received",
+		e.ReadTimeout, len(e.Partial))
+}
+
+// Unwrap returns ErrTimeout.
+func (e *TimeoutError) Unwrap() error { return ErrTimeout }
+
+// Timeout returns true.
+func (e *TimeoutError) Timeout() bool { return true }
+
+// Temporary returns true, since a later read may succeed.
+func (e *TimeoutError) Temporary() bool { return true }
+
+// pollInterval is the read timeout set on the serial port. Reads wait for data
+// in steps of pollInterval, checking the context and the Device's read timeout
+// between steps, so a canceled read returns within about pollInterval without
+// needing a goroutine per read. The last step is shortened to end at the read
+// timeout.
+const pollInterval = 20 * time.Millisecond
+
+// deadlineReader reads from a serial port whose read timeout is pollInterval,
+// waiting until data arrives, the read timeout passes without any data, or the
+// context is done. The Device's buffered reader reads from a deadlineReader, so
+// buffered data is kept when a read is canceled.
+type deadlineReader struct {
+	port    serial.Port
+	ctx     context.Context
+	timeout time.Duration
+}
+
+// Read reads from the port, returning ErrTimeout if no data arrives within the
+// read timeout, or the context error if the context is done first. A negative
+// timeout waits indefinitely.
+func (r *deadlineReader) Read(p []byte) (n int, err error) {
+	deadline := time.Now().Add(r.timeout)
+	shortened := false
+	defer func() {
+		// Restore the port's read timeout if the last step was shortened.
+		if shortened {
+			if rerr := r.port.SetReadTimeout(pollInterval); err == nil && rerr != nil {
+				err = fmt.Errorf("setting read timeout: %w", rerr)
+			}
+		}
+	}()
+	for {
+		if err := r.ctx.Err(); err != nil {
+			return 0, err
+		}
+		if remaining := time.Until(deadline); r.timeout >= 0 && remaining < pollInterval {
+			if err := r.port.SetReadTimeout(max(remaining, 0)); err != nil {
+				return 0, fmt.Errorf("setting read timeout: %w", err)
+			}
+			shortened = true
+		}
+		n, err := r.port.Read(p)
+		if n > 0 || err != nil {
+			return n, err
+		}
+		if r.timeout >= 0 && !time.Now().Before(deadline) {
+			return 0, ErrTimeout
+		}
+	}
+}
+
+// beginRead sets the context and read timeout used by the buffered reader until
+// endRead is called. The caller must hold the lock.
+func (d *Device) beginRead(ctx context.Context, timeout time.Duration) {
+	d.in.ctx = ctx
+	d.in.timeout = timeout
+}
+
+// timeoutError returns err as a *TimeoutError holding the partial data
+// received, if err is ErrTimeout from the deadlineReader, or err otherwise. The
+// caller must hold the lock.
+func (d *Device) timeoutError(err error, partial []byte) error {
+	if !errors.Is(err, ErrTimeout) {
+		return err
+	}
+	return &TimeoutError{Partial: partial, ReadTimeout: d.in.timeout}
+}
+
+// endRead stops the buffered reader from using the context given to
+// beginRead.
+func (d *Device) endRead() {
+	d.in.ctx = context.Background()
+}
+
+// resync discards the input left unread by a failed read, both in the buffered
+// reader and in the port's input buffer, if the Device is stale. If the failed
+// read ended before the read termination, the rest of the response may still
+// be arriving, so resync first reads and discards input up to the read
+// termination, waiting up to ReadTimeout for it. The caller must hold the lock.
+func (d *Device) resync(ctx context.Context) error {
+	start := time.Now()
+	if d.unanswered {
+		n, err := d.drain(ctx)
+		if err != nil {
+			return d.opError("discard", "", n, start, err)
+		}
+		d.logger.DebugContext(ctx, "late response discarded", "n", n)
+		d.unanswered = false
+	}
+	if !d.stale {
+		return nil
+	}
+	if err := d.port.ResetInputBuffer(); err != nil {
+		return d.opError("discard", "", 0, start, err)
+	}
+	d.logger.DebugContext(ctx, "stale input discarded", "buffered", d.reader.Buffered())
+	d.reader.Reset(d.in)
+	d.stale = false
+	return nil
+}
+
+// drain reads and discards input up to the read termination, or until no more
+// arrives if there is none, waiting up to ReadTimeout. It returns the number of
+// bytes discarded. The caller must hold the lock.
+func (d *Device) drain(ctx context.Context) (int, error) {
+	d.beginRead(ctx, d.ReadTimeout())
+	defer d.endRead()
+	var (
+		s   string
+		err error
+	)
+	if term := d.readTermination(); term != "" {
+		s, err = readUntil(d.reader, term)
+	} else {
+		s, err = readUntilQuiet(d.reader)
+	}
+	if errors.Is(err, ErrTimeout) {
+		// The rest of the response didn't arrive in time, so give up on it.
+		err = nil
+	}
+	return len(s), err
+}
@@ -0,0 +1,390 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gotmc/asrl/asrltest"
+)
+
+func TestQueryCanceledWhileWaiting(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument(asrltest.WithResponseDelay(10 * time.Second))
+	inst.Handle("*IDN?", "ACME,1")
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(10*time.Second),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = d.Query(ctx, "*IDN?")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 10*pollInterval {
+		t.Errorf("Query returned %s after it started, want about %s", elapsed, 30*time.Millisecond)
+	}
+}
+
+func TestSetReadTimeoutTakesEffect(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(10*time.Second),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d.SetReadTimeout(30 * time.Millisecond)
+	start := time.Now()
+	_, err = d.Query(context.Background(), "*IDN?")
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want %v", err, ErrTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 10*pollInterval {
+		t.Errorf("Query timed out after %s, want about %s", elapsed, 30*time.Millisecond)
+	}
+}
+
+func TestReadTimeoutIsPrecise(t *testing.T) {
+	t.Parallel()
+
+	// The read timeout isn't a multiple of pollInterval, so data arriving just
+	// after it would be read if the last poll weren't shortened.
+	inst := asrltest.NewInstrument()
+	d, err := NewDeviceFromPort(inst, WithReadTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.AfterFunc(55*time.Millisecond, func() { inst.Send("late") })
+	if _, err := d.ReadBinary(context.Background(), make([]byte, 8)); !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want %v", err, ErrTimeout)
+	}
+}
+
+func TestQueryTimeoutError(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	inst.HandleFunc("*IDN?", func([]string) string {
+		inst.Send("ACME")
+		return ""
+	})
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(30*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = d.Query(context.Background(), "*IDN?")
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("err = %v, want *TimeoutError", err)
+	}
+	if got := string(timeoutErr.Partial); got != "ACME" {
+		t.Errorf("Partial = %q, want %q", got, "ACME")
+	}
+	if timeoutErr.ReadTimeout != 30*time.Millisecond {
+		t.Errorf("ReadTimeout = %s, want %s", timeoutErr.ReadTimeout, 30*time.Millisecond)
+	}
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("errors.Is(err, os.ErrDeadlineExceeded) = false, want true")
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("err = %v, want net.Error with Timeout", err)
+	}
+	if !errors.Is(ErrTimeout, os.ErrDeadlineExceeded) {
+		t.Errorf("errors.Is(ErrTimeout, os.ErrDeadlineExceeded) = false, want true")
+	}
+}
+
+func TestQueryClosedPort(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	d, err := NewDeviceFromPort(inst, WithDelayTime(time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := inst.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = d.Query(context.Background(), "*IDN?")
+	if err == nil || errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want error other than %v", err, ErrTimeout)
+	}
+}
+
+func TestReadBinaryUsesBufferedData(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	inst.Handle("*IDN?", "ACME,1\nEXTRA")
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(100*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	if got, err := d.Query(ctx, "*IDN?"); err != nil || got != "ACME,1\n" {
+		t.Fatalf("Query() = %q, %v, want %q", got, err, "ACME,1\n")
+	}
+	buf := make([]byte, 16)
+	n, err := d.ReadBinary(ctx, buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := string(buf[:n]); got != "EXTRA\n" {
+		t.Errorf("ReadBinary() = %q, want %q", got, "EXTRA\n")
+	}
+	if _, err := d.ReadBinary(ctx, buf); !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want %v", err, ErrTimeout)
+	}
+}
+
+func TestReadWaitsForReadTimeout(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	d, err := NewDeviceFromPort(inst, WithReadTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	const pause = 50 * time.Millisecond
+	time.AfterFunc(pause, func() { inst.Send("x") })
+	buf := make([]byte, 8)
+	start := time.Now()
+	n, err := d.Read(buf)
+	if err != nil || string(buf[:n]) != "x" {
+		t.Fatalf("Read() = %q, %v, want %q", buf[:n], err, "x")
+	}
+	if elapsed := time.Since(start); elapsed < pause {
+		t.Errorf("Read returned after %v, want at least %v", elapsed, pause)
+	}
+
+	// With no data, Read returns nothing once the read timeout passes.
+	d.SetReadTimeout(30 * time.Millisecond)
+	start = time.Now()
+	if n, err := d.Read(buf); n != 0 || err != nil {
+		t.Errorf("Read() = %d, %v, want 0, nil", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Read returned after %v, want at least %v", elapsed, 30*time.Millisecond)
+	}
+}
+
+func TestQueryAfterCanceledQuery(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument(asrltest.WithResponseDelay(30 * time.Millisecond))
+	inst.Handle("FIRST?", "first")
+	inst.Handle("SECOND?", "second")
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := d.Query(ctx, "FIRST?"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// The next query is sent before the late response to FIRST? arrives.
+	got, err := d.Query(context.Background(), "SECOND?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "second\n" {
+		t.Errorf("Query() = %q, want %q", got, "second\n")
+	}
+}
+
+func TestQueryAfterCanceledQueryLateBytes(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	inst.HandleFunc("FIRST?", func([]string) string {
+		time.AfterFunc(30*time.Millisecond, func() { inst.Send("first\n") })
+		return ""
+	})
+	inst.Handle("SECOND?", "second")
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := d.Query(ctx, "FIRST?"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got, err := d.Query(context.Background(), "SECOND?"); err != nil || got != "second\n" {
+		t.Errorf("Query() = %q, %v, want %q", got, err, "second\n")
+	}
+	// The late response was discarded rather than left for a later read.
+	d.SetReadTimeout(20 * time.Millisecond)
+	if _, err := d.ReadBinary(context.Background(), make([]byte, 16)); err == nil {
+		t.Error("late response to FIRST? was not discarded")
+	}
+}
+
+func TestQueryAfterPartialResponse(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	inst.HandleFunc("FIRST?", func([]string) string {
+		inst.Send("fir")
+		return ""
+	})
+	inst.Handle("SECOND?", "second")
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(30*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := d.Query(ctx, "FIRST?"); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("err = %v, want %v", err, ErrTimeout)
+	}
+	// The rest of the response to FIRST? arrives late.
+	inst.Send("st\n")
+	got, err := d.Query(ctx, "SECOND?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "second\n" {
+		t.Errorf("Query() = %q, want %q", got, "second\n")
+	}
+}
+
+// loopbackPort is a serial port that answers every write with a fixed
+// response, returning immediately from Read when there is no data.
+type loopbackPort struct {
+	mockPort
+	mu       sync.Mutex
+	response []byte
+	out      bytes.Buffer
+}
+
+func newLoopbackPort(response string) *loopbackPort {
+	return &loopbackPort{response: []byte(response)}
+}
+
+func (p *loopbackPort) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.out.Write(p.response)
+	return len(b), nil
+}
+
+func (p *loopbackPort) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.out.Len() == 0 {
+		return 0, nil
+	}
+	return p.out.Read(b)
+}
+
+// legacyQuery is the Query read path used before reads were deadline driven,
+// kept to compare against in benchmarks. It reads the response in a goroutine
+// so that the caller can stop waiting when the context is canceled.
+func legacyQuery(ctx context.Context, d *Device, r *bufio.Reader, cmd string) (string, error) {
+	if err := d.command(ctx, cmd); err != nil {
+		return "", err
+	}
+	type result struct {
+		s   string
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		s, err := r.ReadString('\n')
+		ch <- result{s, err}
+	}()
+	select {
+	case <-ctx.Done():
+		_ = d.port.SetReadTimeout(time.Millisecond)
+		<-ch
+		_ = d.port.SetReadTimeout(d.ReadTimeout())
+		r.Reset(d.port)
+		return "", ctx.Err()
+	case res := <-ch:
+		return res.s, res.err
+	}
+}
+
+func newBenchmarkDevice(b *testing.B) *Device {
+	b.Helper()
+	d, err := NewDeviceFromPort(newLoopbackPort("ACME,1\n"), WithDelayTime(0))
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	return d
+}
+
+func BenchmarkQuery(b *testing.B) {
+	d := newBenchmarkDevice(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := d.Query(ctx, "*IDN?"); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func BenchmarkQueryLegacy(b *testing.B) {
+	d := newBenchmarkDevice(b)
+	r := bufio.NewReader(d.port)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := legacyQuery(ctx, d, r, "*IDN?"); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func BenchmarkQueryParallel(b *testing.B) {
+	d := newBenchmarkDevice(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := d.Query(ctx, "*IDN?"); err != nil {
+				b.Errorf("unexpected error: %v", err)
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,341 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// ErrPortLost is returned when the serial port fails, such as when a USB serial
+// adapter is unplugged. With WithReconnect, the port is reopened before the
+// next transaction.
+var ErrPortLost = errors.New("asrl: serial port lost")
+
+// reconnectInterval is how often reopening a lost port is attempted.
+const reconnectInterval = 250 * time.Millisecond
+
+// ReconnectState is the kind of a ReconnectEvent.
+type ReconnectState int
+
+// Reconnect event kinds.
+const (
+	// PortLost is reported when a read, write, or modem status error shows
+	// that the port has been lost.
+	PortLost ReconnectState = iota
+	// PortReconnected is reported when the lost port has been reopened.
+	PortReconnected
+	// ReconnectFailed is reported when the port couldn't be reopened within
+	// the read timeout. Reopening is attempted again before the next
+	// transaction.
+	ReconnectFailed
+)
+
+var reconnectStateNames = map[ReconnectState]string{
+	PortLost:        "lost",
+	PortReconnected: "reconnected",
+	ReconnectFailed: "reconnect failed",
+}
+
+// String returns the name of the reconnect state, such as "lost".
+func (s ReconnectState) String() string {
+	if name, ok := reconnectStateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("ReconnectState(%d)", int(s))
+}
+
+// ReconnectEvent reports a change in the connection of a Device opened with
+// WithReconnect.
+type ReconnectEvent struct {
+	State ReconnectState
+	// Address is the operating system's name for the port that was lost or
+	// reopened. It can change when the port is reopened, if a USB serial
+	// adapter is given a new name when it is plugged back in.
+	Address string
+	// Err is the error that showed the port was lost, or the error reopening
+	// it, and is nil for PortReconnected.
+	Err error
+}
+
+// WithReconnect enables reopening the serial port when it is lost, such as
+// when a USB serial adapter is unplugged and plugged back in. Once a read,
+// write, or modem status error shows the port has been lost, the failed method
+// returns an error wrapping ErrPortLost, and the next transaction first reopens
+// the port, trying every 250 ms for up to the read timeout. If the port is a
+// USB serial adapter with a serial number, the port with that serial number is
+// reopened, even if its name has changed. The reopened port is given the
+// Device's current settings, such as the baud rate and flow control, and any
+// data buffered from the lost port is discarded.
+//
+// The function fn, if not nil, is called with each ReconnectEvent. It is
+// called while the Device is in use, so it must not call the Device's methods.
+//
+// WithReconnect only applies to Devices opened by NewDevice or AutoDetect,
+// since the other constructors don't know how to reopen the port. A Device
+// opened by AutoDetect only reconnects once detection has succeeded, and
+// reopens the port with the detected settings.
+func WithReconnect(fn func(ReconnectEvent)) DeviceOption {
+	return func(d *Device) {
+		d.reconnect = true
+		d.onReconnect = fn
+	}
+}
+
+// notifyReconnect logs the event and passes it to the WithReconnect callback.
+func (d *Device) notifyReconnect(ev ReconnectEvent) {
+	d.logger.Debug("port "+ev.State.String(), "address", ev.Address, "err", ev.Err)
+	if d.onReconnect != nil {
+		d.onReconnect(ev)
+	}
+}
+
+// reopen reopens the serial port if it has been lost, trying until it
+// succeeds, the read timeout elapses, or the context is done. The caller must
+// hold the lock.
+func (d *Device) reopen(ctx context.Context) error {
+	if d.link == nil || !d.link.isLost() {
+		return nil
+	}
+	start := time.Now()
+	timeout := d.ReadTimeout()
+	for {
+		address, err := d.link.reopen(d.serialMode())
+		if err == nil {
+			// Anything buffered came from the lost port.
+			d.stale = true
+			err = d.applyFlowControl()
+		}
+		if err == nil {
+			d.logger.DebugContext(ctx, "port reopened",
+				"address", address, "elapsed", time.Since(start))
+			d.notifyReconnect(ReconnectEvent{State: PortReconnected, Address: address})
+			return nil
+		}
+		if timeout >= 0 && time.Since(start) >= timeout {
+			d.notifyReconnect(ReconnectEvent{State: ReconnectFailed, Address: address, Err: err})
+			err = fmt.Errorf("%w: reopening %s: %w", ErrPortLost, address, err)
+			return d.opError("open", "", 0, start, err)
+		}
+		if err := sleepContext(ctx, reconnectInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// enableReconnect inserts a reconnectPort for the given address between port,
+// the serial port the Device was created with, and the ports wrapping it. The
+// caller must have sole use of the Device.
+func (d *Device) enableReconnect(port serial.Port, address string) {
+	d.link = newReconnectPort(port, address, d.notifyReconnect)
+	if cp, ok := d.xon.Port.(*capturePort); ok {
+		cp.Port = d.link
+	} else {
+		d.xon.Port = d.link
+	}
+}
+
+// reconnectPort is a serial.Port whose underlying port can be replaced after
+// it is lost. A read, write, or modem status error marks the port as lost.
+type reconnectPort struct {
+	// address and serialNumber identify the port to reopen.
+	address      string
+	serialNumber string
+	notify       func(ReconnectEvent)
+
+	mu      sync.Mutex
+	port    serial.Port
+	lostErr error
+	closed  bool
+}
+
+// newReconnectPort wraps the port opened at the given address, looking up its
+// USB serial number, if any, so that it can be found again if renamed.
+func newReconnectPort(
+	port serial.Port,
+	address string,
+	notify func(ReconnectEvent),
+) *reconnectPort {
+	p := &reconnectPort{port: port, address: address, notify: notify}
+	if pd, ok := portDetails()[address]; ok && pd.IsUSB {
+		p.serialNumber = pd.SerialNumber
+	}
+	return p
+}
+
+// isLost reports whether the port has been lost and not yet reopened.
+func (p *reconnectPort) isLost() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lostErr != nil && !p.closed
+}
+
+// current returns the underlying port, or the error that showed it was lost.
+func (p *reconnectPort) current() (serial.Port, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lostErr != nil {
+		return nil, p.lostErr
+	}
+	return p.port, nil
+}
+
+// check marks the port as lost if err isn't nil, returning err wrapped with
+// ErrPortLost.
+func (p *reconnectPort) check(err error) error {
+	if err == nil {
+		return nil
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return err
+	}
+	first := p.lostErr == nil
+	if first {
+		p.lostErr = fmt.Errorf("%w: %w", ErrPortLost, err)
+	}
+	lostErr, address := p.lostErr, p.address
+	p.mu.Unlock()
+	if first {
+		p.notify(ReconnectEvent{State: PortLost, Address: address, Err: err})
+	}
+	return lostErr
+}
+
+// reopen closes the lost port and opens the port with the same USB serial
+// number or, failing that, the same address, returning the address opened.
+func (p *reconnectPort) reopen(mode *serial.Mode) (string, error) {
+	p.mu.Lock()
+	old := p.port
+	p.port = nil
+	p.mu.Unlock()
+	if old != nil {
+		_ = old.Close()
+	}
+
+	address := p.find()
+	port, err := openPort(address, mode)
+	if err != nil {
+		return address, err
+	}
+	if err := port.SetReadTimeout(pollInterval); err != nil {
+		_ = port.Close()
+		return address, fmt.Errorf("setting read timeout: %w", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		_ = port.Close()
+		return address, errors.New("asrl: device closed")
+	}
+	p.port = port
+	p.address = address
+	p.lostErr = nil
+	return address, nil
+}
+
+// find returns the name of the port with the USB serial number of the lost
+// port, or its last known address.
+func (p *reconnectPort) find() string {
+	if p.serialNumber == "" {
+		return p.address
+	}
+	list, err := getDetailedPortsList()
+	if err != nil {
+		return p.address
+	}
+	for _, pd := range list {
+		if pd != nil && pd.IsUSB && pd.SerialNumber == p.serialNumber {
+			return pd.Name
+		}
+	}
+	return p.address
+}
+
+func (p *reconnectPort) Read(b []byte) (int, error) {
+	port, err := p.current()
+	if err != nil {
+		return 0, err
+	}
+	n, err := port.Read(b)
+	return n, p.check(err)
+}
+
+func (p *reconnectPort) Write(b []byte) (int, error) {
+	port, err := p.current()
+	if err != nil {
+		return 0, err
+	}
+	n, err := port.Write(b)
+	return n, p.check(err)
+}
+
+func (p *reconnectPort) GetModemStatusBits() (*serial.ModemStatusBits, error) {
+	port, err := p.current()
+	if err != nil {
+		return nil, err
+	}
+	bits, err := port.GetModemStatusBits()
+	return bits, p.check(err)
+}
+
+// do calls fn with the underlying port, unless it has been lost.
+func (p *reconnectPort) do(fn func(serial.Port) error) error {
+	port, err := p.current()
+	if err != nil {
+		return err
+	}
+	return fn(port)
+}
+
+func (p *reconnectPort) SetMode(mode *serial.Mode) error {
+	return p.do(func(port serial.Port) error { return port.SetMode(mode) })
+}
+
+func (p *reconnectPort) Drain() error {
+	return p.do(serial.Port.Drain)
+}
+
+func (p *reconnectPort) ResetInputBuffer() error {
+	return p.do(serial.Port.ResetInputBuffer)
+}
+
+func (p *reconnectPort) ResetOutputBuffer() error {
+	return p.do(serial.Port.ResetOutputBuffer)
+}
+
+func (p *reconnectPort) SetDTR(dtr bool) error {
+	return p.do(func(port serial.Port) error { return port.SetDTR(dtr) })
+}
+
+func (p *reconnectPort) SetRTS(rts bool) error {
+	return p.do(func(port serial.Port) error { return port.SetRTS(rts) })
+}
+
+func (p *reconnectPort) SetReadTimeout(t time.Duration) error {
+	return p.do(func(port serial.Port) error { return port.SetReadTimeout(t) })
+}
+
+func (p *reconnectPort) Break(t time.Duration) error {
+	return p.do(func(port serial.Port) error { return port.Break(t) })
+}
+
+// Close closes the underlying port, if it hasn't been lost.
+func (p *reconnectPort) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	if p.port == nil {
+		return nil
+	}
+	return p.port.Close()
+}
@@ -0,0 +1,237 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gotmc/asrl/asrltest"
+	"go.bug.st/serial"
+	"go.bug.st/serial/enumerator"
+)
+
+// fakeUSBPorts replaces the serial library with one whose ports are the given
+// instruments, each with a USB serial number, and which can be unplugged and
+// plugged back in. Tests using it must not run in parallel.
+type fakeUSBPorts struct {
+	mu    sync.Mutex
+	ports map[string]*asrltest.Instrument
+	usbSN map[string]string
+}
+
+func newFakeUSBPorts(t *testing.T) *fakeUSBPorts {
+	t.Helper()
+	f := &fakeUSBPorts{
+		ports: map[string]*asrltest.Instrument{},
+		usbSN: map[string]string{},
+	}
+	fakeSerialLibrary(t, nil, nil, func(name string, mode *serial.Mode) (serial.Port, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		inst, ok := f.ports[name]
+		if !ok {
+			return nil, errors.New("no such file or directory")
+		}
+		if err := inst.SetMode(mode); err != nil {
+			return nil, err
+		}
+		return inst, nil
+	})
+	getDetailedPortsList = func() ([]*enumerator.PortDetails, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		var list []*enumerator.PortDetails
+		for name, sn := range f.usbSN {
+			list = append(list, &enumerator.PortDetails{Name: name, IsUSB: true, SerialNumber: sn})
+		}
+		return list, nil
+	}
+	return f
+}
+
+func (f *fakeUSBPorts) plug(name, serialNumber string, inst *asrltest.Instrument) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ports[name] = inst
+	f.usbSN[name] = serialNumber
+}
+
+func (f *fakeUSBPorts) unplug(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_ = f.ports[name].Close()
+	delete(f.ports, name)
+	delete(f.usbSN, name)
+}
+
+func newIDNInstrument(idn string) *asrltest.Instrument {
+	inst := asrltest.NewInstrument()
+	inst.Handle("*IDN?", idn)
+	return inst
+}
+
+func TestReconnect(t *testing.T) {
+	ports := newFakeUSBPorts(t)
+	ports.plug("/dev/ttyUSB0", "FT1234", newIDNInstrument("ACME,1"))
+	var events []ReconnectEvent
+	ctx := context.Background()
+	d, err := NewDevice(ctx, "ASRL::/dev/ttyUSB0::19200::7E1::INSTR",
+		WithFlowControl(FlowRTSCTS),
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(time.Second),
+		WithReconnect(func(ev ReconnectEvent) { events = append(events, ev) }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer d.Close()
+	if _, err := d.Query(ctx, "*IDN?"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ports.unplug("/dev/ttyUSB0")
+	if _, err := d.Query(ctx, "*IDN?"); !errors.Is(err, ErrPortLost) {
+		t.Fatalf("err = %v, want %v", err, ErrPortLost)
+	}
+	if len(events) != 1 || events[0].State != PortLost || events[0].Err == nil {
+		t.Fatalf("events = %+v, want one %s event", events, PortLost)
+	}
+
+	// The adapter comes back under a different name.
+	inst := newIDNInstrument("ACME,2")
+	ports.plug("/dev/ttyUSB1", "FT1234", inst)
+	got, err := d.Query(ctx, "*IDN?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "ACME,2\n" {
+		t.Errorf("Query() = %q, want %q", got, "ACME,2\n")
+	}
+	if len(events) != 2 || events[1].State != PortReconnected ||
+		events[1].Address != "/dev/ttyUSB1" {
+		t.Errorf("events = %+v, want %s event for /dev/ttyUSB1", events, PortReconnected)
+	}
+	mode := inst.Mode()
+	if mode.BaudRate != 19200 || mode.DataBits != 7 || mode.Parity != serial.EvenParity {
+		t.Errorf("Mode() = %+v, want 19200 7E1", mode)
+	}
+	if !inst.RTS() {
+		t.Error("RTS not asserted after reconnecting")
+	}
+}
+
+func TestReconnectAutoDetect(t *testing.T) {
+	ports := newFakeUSBPorts(t)
+	ports.plug("/dev/ttyUSB0", "FT1234", newIDNInstrument("ACME,1"))
+	var events []ReconnectEvent
+	ctx := context.Background()
+	d, err := AutoDetect(ctx, "/dev/ttyUSB0", "*IDN?",
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(time.Second),
+		WithReconnect(func(ev ReconnectEvent) { events = append(events, ev) }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	ports.unplug("/dev/ttyUSB0")
+	if _, err := d.Query(ctx, "*IDN?"); !errors.Is(err, ErrPortLost) {
+		t.Fatalf("err = %v, want %v", err, ErrPortLost)
+	}
+	inst := newIDNInstrument("ACME,2")
+	ports.plug("/dev/ttyUSB0", "FT1234", inst)
+	got, err := d.Query(ctx, "*IDN?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "ACME,2\n" {
+		t.Errorf("Query() = %q, want %q", got, "ACME,2\n")
+	}
+	if len(events) != 2 || events[1].State != PortReconnected {
+		t.Errorf("events = %+v, want %s event", events, PortReconnected)
+	}
+	if mode := inst.Mode(); mode.BaudRate != 9600 || mode.DataBits != 8 {
+		t.Errorf("Mode() = %+v, want 9600 8N1", mode)
+	}
+}
+
+func TestReconnectFailed(t *testing.T) {
+	ports := newFakeUSBPorts(t)
+	ports.plug("/dev/ttyUSB0", "FT1234", newIDNInstrument("ACME,1"))
+	var events []ReconnectEvent
+	ctx := context.Background()
+	d, err := NewDevice(ctx, "ASRL::/dev/ttyUSB0::9600::8N1::INSTR",
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(100*time.Millisecond),
+		WithReconnect(func(ev ReconnectEvent) { events = append(events, ev) }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	ports.unplug("/dev/ttyUSB0")
+	if err := d.Command(ctx, "*RST"); !errors.Is(err, ErrPortLost) {
+		t.Fatalf("err = %v, want %v", err, ErrPortLost)
+	}
+	if _, err := d.Query(ctx, "*IDN?"); !errors.Is(err, ErrPortLost) {
+		t.Fatalf("err = %v, want %v", err, ErrPortLost)
+	}
+	if len(events) != 2 || events[1].State != ReconnectFailed || events[1].Err == nil {
+		t.Fatalf("events = %+v, want %s event", events, ReconnectFailed)
+	}
+
+	// Reopening is tried again by the next transaction.
+	ports.plug("/dev/ttyUSB0", "FT1234", newIDNInstrument("ACME,2"))
+	if got, err := d.Query(ctx, "*IDN?"); err != nil || got != "ACME,2\n" {
+		t.Errorf("Query() = %q, %v, want %q", got, err, "ACME,2\n")
+	}
+}
+
+func TestReconnectDisabled(t *testing.T) {
+	ports := newFakeUSBPorts(t)
+	ports.plug("/dev/ttyUSB0", "FT1234", newIDNInstrument("ACME,1"))
+	ctx := context.Background()
+	d, err := NewDevice(ctx, "ASRL::/dev/ttyUSB0::9600::8N1::INSTR",
+		WithDelayTime(time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	ports.unplug("/dev/ttyUSB0")
+	ports.plug("/dev/ttyUSB0", "FT1234", newIDNInstrument("ACME,2"))
+	for range 2 {
+		if _, err := d.Query(ctx, "*IDN?"); !errors.Is(err, asrltest.ErrClosed) {
+			t.Errorf("err = %v, want %v", err, asrltest.ErrClosed)
+		}
+	}
+}
+
+func TestReconnectStateString(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		state ReconnectState
+		want  string
+	}{
+		{PortLost, "lost"},
+		{PortReconnected, "reconnected"},
+		{ReconnectFailed, "reconnect failed"},
+		{ReconnectState(7), "ReconnectState(7)"},
+	}
+	for _, tc := range testCases {
+		if got := tc.state.String(); got != tc.want {
+			t.Errorf("String() = %q, want %q", got, tc.want)
+		}
+	}
+}
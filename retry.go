@@ -0,0 +1,139 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrEmptyResponse is passed to RetryPolicy.Retryable when Query reads a
+// response that is empty or only whitespace. If the empty response isn't
+// retried, Query returns it without an error, as it does without a retry
+// policy.
+var ErrEmptyResponse = errors.New("asrl: empty response")
+
+// RetryPolicy controls how failed Query and Command calls are retried. Before
+// each retry, the rest of the failed attempt's response is read up to the read
+// termination, waiting up to the read timeout, and discarded along with any
+// other input, so a late response isn't mistaken for the retry's. The zero
+// value doesn't retry.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts made, including the first. Values
+	// less than two don't retry.
+	MaxAttempts int
+	// Backoff is how long to wait before the first retry. The wait doubles for
+	// each later retry.
+	Backoff time.Duration
+	// Retryable reports whether an attempt that failed with the given error
+	// should be retried. If nil, DefaultRetryable is used.
+	Retryable func(error) bool
+	// Commands enables retrying Command as well as Query. Retrying a command
+	// can send it more than once, so only enable it for idempotent commands,
+	// such as those setting a value, and not for commands such as *TRG or
+	// INIT.
+	Commands bool
+}
+
+// DefaultRetryable reports whether err is one typically caused by a noisy
+// line: a read timeout, including one part way through a response, an empty
+// response, a flow control signal that wasn't asserted in time, or a lost
+// port. Context errors and SCPI errors aren't retryable.
+func DefaultRetryable(err error) bool {
+	for _, target := range []error{
+		ErrTimeout,
+		ErrEmptyResponse,
+		ErrDSRNotReady,
+		ErrCTSNotReady,
+		ErrXONNotReceived,
+		ErrPortLost,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable reports whether the policy retries err.
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable == nil {
+		return DefaultRetryable(err)
+	}
+	return p.Retryable(err)
+}
+
+// WithRetry sets the policy used to retry Query and, if the policy's Commands
+// field is set, Command. ContextWithRetry overrides it for a single call.
+func WithRetry(p RetryPolicy) DeviceOption {
+	return func(d *Device) {
+		d.retry = p
+	}
+}
+
+// RetryPolicy returns the policy used to retry Query and Command.
+func (d *Device) RetryPolicy() RetryPolicy {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.retry
+}
+
+// SetRetryPolicy sets the policy used to retry Query and Command.
+func (d *Device) SetRetryPolicy(p RetryPolicy) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.retry = p
+}
+
+// retryKey is the context key for a per-call retry policy.
+type retryKey struct{}
+
+// ContextWithRetry returns a context that makes Query and Command use the given
+// retry policy instead of the Device's. For example, to retry an idempotent
+// command:
+//
+//	ctx = asrl.ContextWithRetry(ctx, asrl.RetryPolicy{MaxAttempts: 3, Commands: true})
+//	err := dev.Command(ctx, "VOLT 5")
+//
+// The zero RetryPolicy disables retrying for the call.
+func ContextWithRetry(ctx context.Context, p RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryKey{}, p)
+}
+
+// retryPolicy returns the retry policy carried by ctx, or the Device's policy.
+func (d *Device) retryPolicy(ctx context.Context) RetryPolicy {
+	if p, ok := ctx.Value(retryKey{}).(RetryPolicy); ok {
+		return p
+	}
+	return d.RetryPolicy()
+}
+
+// withRetry calls fn until it succeeds, fails with an error the policy doesn't
+// retry, or the attempts run out, returning the last error. Before each retry,
+// it waits for the backoff and discards any input, including a late response
+// to the failed attempt. The caller must hold the lock.
+func (d *Device) withRetry(ctx context.Context, p RetryPolicy, fn func() error) error {
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || ctx.Err() != nil || !p.retryable(err) {
+			return err
+		}
+		d.logger.DebugContext(ctx, "retrying", "attempt", attempt, "err", err)
+		if err := sleepContext(ctx, backoff); err != nil {
+			return err
+		}
+		backoff *= 2
+		d.stale = true
+		if err := d.reopen(ctx); err != nil {
+			return err
+		}
+		if err := d.resync(ctx); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gotmc/asrl/asrltest"
+)
+
+// newFlakyInstrument returns an instrument that calls fail instead of answering
+// the first failures *IDN? queries, after which it answers normally.
+func newFlakyInstrument(failures int, fail func(*asrltest.Instrument)) *asrltest.Instrument {
+	inst := asrltest.NewInstrument()
+	var calls atomic.Int32
+	inst.HandleFunc("*IDN?", func([]string) string {
+		if int(calls.Add(1)) <= failures {
+			fail(inst)
+			return ""
+		}
+		return "ACME,1"
+	})
+	return inst
+}
+
+func newRetryDevice(t *testing.T, inst *asrltest.Instrument, opts ...DeviceOption) *Device {
+	t.Helper()
+	opts = append([]DeviceOption{
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(30 * time.Millisecond),
+	}, opts...)
+	d, err := NewDeviceFromPort(inst, opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return d
+}
+
+func TestQueryRetry(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		fail func(*asrltest.Instrument)
+	}{
+		"no response":      {func(*asrltest.Instrument) {}},
+		"empty response":   {func(inst *asrltest.Instrument) { inst.Send(" \n") }},
+		"partial response": {func(inst *asrltest.Instrument) { inst.Send("AC") }},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			inst := newFlakyInstrument(2, tc.fail)
+			d := newRetryDevice(t, inst, WithRetry(RetryPolicy{
+				MaxAttempts: 3,
+				Backoff:     time.Millisecond,
+			}))
+			got, err := d.Query(context.Background(), "*IDN?")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != "ACME,1\n" {
+				t.Errorf("Query() = %q, want %q", got, "ACME,1\n")
+			}
+			if n := len(inst.Commands()); n != 3 {
+				t.Errorf("sent %d commands, want 3", n)
+			}
+		})
+	}
+}
+
+func TestQueryRetryLateResponse(t *testing.T) {
+	t.Parallel()
+
+	// The response to the first attempt arrives after it has timed out.
+	inst := newFlakyInstrument(1, func(inst *asrltest.Instrument) {
+		time.AfterFunc(130*time.Millisecond, func() { inst.Send("ACME,1\n") })
+	})
+	inst.HandleFunc("CURR?", func([]string) string {
+		time.AfterFunc(50*time.Millisecond, func() { inst.Send("1.0\n") })
+		return ""
+	})
+	d := newRetryDevice(t, inst,
+		WithReadTimeout(100*time.Millisecond),
+		WithRetry(RetryPolicy{MaxAttempts: 3}),
+	)
+	ctx := context.Background()
+	if got, err := d.Query(ctx, "*IDN?"); err != nil || got != "ACME,1\n" {
+		t.Fatalf("Query() = %q, %v, want %q", got, err, "ACME,1\n")
+	}
+	// The late response isn't read as the response to the next query.
+	if got, err := d.Query(ctx, "CURR?"); err != nil || got != "1.0\n" {
+		t.Errorf("Query() = %q, %v, want %q", got, err, "1.0\n")
+	}
+}
+
+func TestQueryRetryExhausted(t *testing.T) {
+	t.Parallel()
+
+	inst := newFlakyInstrument(5, func(inst *asrltest.Instrument) { inst.Send("\n") })
+	d := newRetryDevice(t, inst, WithRetry(RetryPolicy{MaxAttempts: 3}))
+	got, err := d.Query(context.Background(), "*IDN?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "\n" {
+		t.Errorf("Query() = %q, want %q", got, "\n")
+	}
+	if n := len(inst.Commands()); n != 3 {
+		t.Errorf("sent %d commands, want 3", n)
+	}
+
+	if _, err := d.Query(context.Background(), "NONE?"); !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want %v", err, ErrTimeout)
+	}
+	if n := len(inst.Commands()); n != 6 {
+		t.Errorf("sent %d commands, want 6", n)
+	}
+}
+
+func TestQueryRetryNotRetryable(t *testing.T) {
+	t.Parallel()
+
+	inst := newFlakyInstrument(1, func(*asrltest.Instrument) {})
+	d := newRetryDevice(t, inst, WithRetry(RetryPolicy{
+		MaxAttempts: 3,
+		Retryable:   func(error) bool { return false },
+	}))
+	if _, err := d.Query(context.Background(), "*IDN?"); !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want %v", err, ErrTimeout)
+	}
+	if n := len(inst.Commands()); n != 1 {
+		t.Errorf("sent %d commands, want 1", n)
+	}
+}
+
+func TestQueryRetryContext(t *testing.T) {
+	t.Parallel()
+
+	inst := newFlakyInstrument(1, func(*asrltest.Instrument) {})
+	d := newRetryDevice(t, inst, WithRetry(RetryPolicy{MaxAttempts: 3}))
+	ctx := ContextWithRetry(context.Background(), RetryPolicy{})
+	if _, err := d.Query(ctx, "*IDN?"); !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want %v", err, ErrTimeout)
+	}
+	if n := len(inst.Commands()); n != 1 {
+		t.Errorf("sent %d commands, want 1", n)
+	}
+}
+
+func TestCommandRetry(t *testing.T) {
+	t.Parallel()
+
+	errNoise := errors.New("framing error")
+	policy := RetryPolicy{
+		MaxAttempts: 2,
+		Retryable:   func(err error) bool { return errors.Is(err, errNoise) },
+	}
+	inst := asrltest.NewInstrument()
+	d := newRetryDevice(t, inst, WithRetry(policy))
+	ctx := context.Background()
+
+	// Commands aren't retried unless the policy says they are idempotent.
+	inst.InjectWriteError(errNoise)
+	if err := d.Command(ctx, "VOLT 1"); !errors.Is(err, errNoise) {
+		t.Errorf("err = %v, want %v", err, errNoise)
+	}
+
+	policy.Commands = true
+	inst.InjectWriteError(errNoise)
+	if err := d.Command(ContextWithRetry(ctx, policy), "VOLT %d", 2); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got := inst.Commands(); len(got) != 1 || got[0] != "VOLT 2" {
+		t.Errorf("Commands() = %q, want %q", got, []string{"VOLT 2"})
+	}
+}
+
+func TestQueryRetryCanceled(t *testing.T) {
+	t.Parallel()
+
+	inst := newFlakyInstrument(5, func(*asrltest.Instrument) {})
+	d := newRetryDevice(t, inst, WithRetry(RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     time.Second,
+	}))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := d.Query(ctx, "*IDN?"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if n := len(inst.Commands()); n != 1 {
+		t.Errorf("sent %d commands, want 1", n)
+	}
+}
+
+func TestDefaultRetryable(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		err  error
+		want bool
+	}{
+		{ErrTimeout, true},
+		{&TimeoutError{Partial: []byte("AC")}, true},
+		{io.EOF, false},
+		{ErrEmptyResponse, true},
+		{fmt.Errorf("%w after 5s", ErrDSRNotReady), true},
+		{ErrCTSNotReady, true},
+		{ErrXONNotReceived, true},
+		{ErrPortLost, true},
+		{context.Canceled, false},
+		{&SCPIError{Code: -113, Message: "Undefined header"}, false},
+		{errors.New("other"), false},
+	}
+	for _, tc := range testCases {
+		if got := DefaultRetryable(tc.err); got != tc.want {
+			t.Errorf("DefaultRetryable(%v) = %t, want %t", tc.err, got, tc.want)
+		}
+	}
+}
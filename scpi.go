@@ -0,0 +1,118 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// errorQuery is the SCPI query that reads the next entry in the error queue.
+const errorQuery = "SYST:ERR?"
+
+// maxErrorQueue limits how many entries DrainErrors reads, in case an
+// instrument never reports an empty error queue.
+const maxErrorQueue = 32
+
+// SCPIError is an entry read from an instrument's SCPI error queue, such as
+// -113,"Undefined header".
+type SCPIError struct {
+	// Code is the SCPI error number, which is negative for standard errors and
+	// positive for instrument specific errors.
+	Code int
+	// Message is the error description, without quotes.
+	Message string
+	// Command is the command that was sent before the error was read, or empty
+	// if unknown, such as when DrainErrors is called after a batch of commands.
+	Command string
+}
+
+func (e *SCPIError) Error() string {
+	if e.Command == "" {
+		return fmt.Sprintf("asrl: SCPI error %d, %q", e.Code, e.Message)
+	}
+	return fmt.Sprintf("asrl: SCPI error %d, %q after %q", e.Code, e.Message, e.Command)
+}
+
+// WithErrorChecking enables or disables draining the instrument's SCPI error
+// queue with SYST:ERR? after every Command and CommandBinaryBlock. Any errors
+// found are returned as *SCPIError values. Error checking doubles the number of
+// transactions, so to check errors after a batch of commands instead, leave it
+// disabled and call DrainErrors after the batch.
+func WithErrorChecking(enabled bool) DeviceOption {
+	return func(d *Device) {
+		d.errorChecking = enabled
+	}
+}
+
+// ErrorChecking returns whether the SCPI error queue is drained after every
+// command.
+func (d *Device) ErrorChecking() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.errorChecking
+}
+
+// SetErrorChecking enables or disables draining the SCPI error queue after
+// every command.
+func (d *Device) SetErrorChecking(enabled bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.errorChecking = enabled
+}
+
+// DrainErrors reads the instrument's SCPI error queue using SYST:ERR? until it
+// reports 0,"No error", and returns the errors read as *SCPIError values
+// joined using errors.Join, so they can be inspected with errors.As. It returns
+// nil if the error queue is empty.
+func (d *Device) DrainErrors(ctx context.Context) error {
+	release, err := d.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return d.drainErrors(ctx, "")
+}
+
+// drainErrors reads the error queue, attributing any errors to cmd. The caller
+// must hold the lock.
+func (d *Device) drainErrors(ctx context.Context, cmd string) error {
+	var errs []error
+	for range maxErrorQueue {
+		resp, err := d.query(ctx, errorQuery, d.ReadTimeout())
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		scpiErr, err := parseSCPIError(resp)
+		if err != nil {
+			err = &ResponseError{Command: errorQuery, Response: resp, Err: err}
+			return errors.Join(append(errs, err)...)
+		}
+		if scpiErr.Code == 0 {
+			return errors.Join(errs...)
+		}
+		scpiErr.Command = cmd
+		d.logger.DebugContext(ctx, "SCPI error",
+			"code", scpiErr.Code, "message", scpiErr.Message, "cmd", cmd)
+		errs = append(errs, scpiErr)
+	}
+	return errors.Join(errs...)
+}
+
+// parseSCPIError parses an error queue entry, such as -113,"Undefined header".
+// Some instruments append details after a semicolon inside the quotes, which
+// are kept in the message.
+func parseSCPIError(resp string) (*SCPIError, error) {
+	code, msg, _ := strings.Cut(strings.TrimSpace(resp), ",")
+	n, err := strconv.Atoi(strings.TrimSpace(code))
+	if err != nil {
+		return nil, err
+	}
+	return &SCPIError{Code: n, Message: unquote(strings.TrimSpace(msg))}, nil
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gotmc/asrl/asrltest"
+)
+
+// newErrorQueueInstrument returns an instrument that queues a SCPI error for
+// every command matching a key of bad and reports the queue with SYST:ERR?.
+// Other queries are answered with 1.
+func newErrorQueueInstrument(bad map[string]string) *asrltest.Instrument {
+	var (
+		mu    sync.Mutex
+		queue []string
+	)
+	inst := asrltest.NewInstrument()
+	inst.HandleFunc("SYST:ERR?", func(_ []string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(queue) == 0 {
+			return `+0,"No error"`
+		}
+		e := queue[0]
+		queue = queue[1:]
+		return e
+	})
+	inst.HandleRegexpFunc(`.*`, func(m []string) string {
+		if e, ok := bad[m[0]]; ok {
+			mu.Lock()
+			queue = append(queue, e)
+			mu.Unlock()
+		}
+		if strings.HasSuffix(m[0], "?") {
+			return "1"
+		}
+		return ""
+	})
+	return inst
+}
+
+func TestCommandErrorChecking(t *testing.T) {
+	t.Parallel()
+
+	inst := newErrorQueueInstrument(map[string]string{
+		"VOLT 99": `-222,"Data out of range"`,
+		"VOLT?":   `-100,"Command error"`,
+	})
+	d, err := NewDeviceFromPort(inst,
+		WithErrorChecking(true),
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !d.ErrorChecking() {
+		t.Error("ErrorChecking() = false, want true")
+	}
+	ctx := context.Background()
+
+	if err := d.Command(ctx, "VOLT %d", 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = d.Command(ctx, "VOLT %d", 99)
+	var scpiErr *SCPIError
+	if !errors.As(err, &scpiErr) {
+		t.Fatalf("err = %v, want *SCPIError", err)
+	}
+	want := SCPIError{Code: -222, Message: "Data out of range", Command: "VOLT 99"}
+	if *scpiErr != want {
+		t.Errorf("SCPIError = %+v, want %+v", *scpiErr, want)
+	}
+
+	// Queries don't check the error queue.
+	if _, err := d.Query(ctx, "VOLT?"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.DrainErrors(ctx); !errors.As(err, &scpiErr) || scpiErr.Code != -100 {
+		t.Errorf("err = %v, want SCPI error -100", err)
+	}
+}
+
+func TestDrainErrors(t *testing.T) {
+	t.Parallel()
+
+	inst := newErrorQueueInstrument(map[string]string{
+		"FOO": `-113,"Undefined header"`,
+		"BAR": `-113,"Undefined header;BAR"`,
+	})
+	d, err := NewDeviceFromPort(inst,
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	if err := d.DrainErrors(ctx); err != nil {
+		t.Fatalf("DrainErrors() = %v, want nil", err)
+	}
+
+	// Errors in a batch of commands are only reported by DrainErrors.
+	for _, cmd := range []string{"FOO", "OUTP ON", "BAR"} {
+		if err := d.Command(ctx, cmd); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	err = d.DrainErrors(ctx)
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		t.Fatalf("err = %v, want joined errors", err)
+	}
+	var got []SCPIError
+	for _, e := range joined.Unwrap() {
+		var scpiErr *SCPIError
+		if !errors.As(e, &scpiErr) {
+			t.Fatalf("err = %v, want *SCPIError", e)
+		}
+		got = append(got, *scpiErr)
+	}
+	want := []SCPIError{
+		{Code: -113, Message: "Undefined header"},
+		{Code: -113, Message: "Undefined header;BAR"},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("DrainErrors() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDrainErrorsInvalidResponse(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDevice(newMockPort("garbage\n"))
+	err := d.DrainErrors(context.Background())
+	var re *ResponseError
+	if !errors.As(err, &re) {
+		t.Errorf("err = %v, want *ResponseError", err)
+	}
+}
+
+func TestSCPIErrorString(t *testing.T) {
+	t.Parallel()
+
+	e := &SCPIError{Code: -113, Message: "Undefined header"}
+	if got, want := e.Error(), `asrl: SCPI error -113, "Undefined header"`; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	e.Command = "FOO"
+	want := `asrl: SCPI error -113, "Undefined header" after "FOO"`
+	if got := e.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
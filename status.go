@@ -0,0 +1,136 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// errInvalidRegister is wrapped by the ResponseError returned when a status
+// register response isn't an integer from 0 to 255.
+var errInvalidRegister = errors.New("register value out of range")
+
+// StatusByte is the IEEE 488.2 status byte register read using *STB?, with the
+// SCPI summary bits.
+type StatusByte uint8
+
+// Status byte bits.
+const (
+	StatusEAV  StatusByte = 1 << 2 // Error/event queue not empty
+	StatusQUES StatusByte = 1 << 3 // Questionable status summary
+	StatusMAV  StatusByte = 1 << 4 // Message available
+	StatusESB  StatusByte = 1 << 5 // Standard event status summary
+	StatusRQS  StatusByte = 1 << 6 // Request service, or master summary status
+	StatusOPER StatusByte = 1 << 7 // Operation status summary
+)
+
+var statusByteNames = [8]string{
+	2: "EAV", 3: "QUES", 4: "MAV", 5: "ESB", 6: "RQS", 7: "OPER",
+}
+
+// Has reports whether all of the given bits are set.
+func (s StatusByte) Has(bits StatusByte) bool { return s&bits == bits }
+
+// String returns the names of the bits that are set separated by |, such as
+// "MAV|ESB", or "0" if no bits are set.
+func (s StatusByte) String() string { return registerString(uint8(s), statusByteNames) }
+
+// StandardEventStatus is the IEEE 488.2 standard event status register read
+// using *ESR?, and the mask set using *ESE.
+type StandardEventStatus uint8
+
+// Standard event status register bits.
+const (
+	EventOPC StandardEventStatus = 1 << 0 // Operation complete
+	EventRQC StandardEventStatus = 1 << 1 // Request control
+	EventQYE StandardEventStatus = 1 << 2 // Query error
+	EventDDE StandardEventStatus = 1 << 3 // Device dependent error
+	EventEXE StandardEventStatus = 1 << 4 // Execution error
+	EventCME StandardEventStatus = 1 << 5 // Command error
+	EventURQ StandardEventStatus = 1 << 6 // User request
+	EventPON StandardEventStatus = 1 << 7 // Power on
+)
+
+var eventStatusNames = [8]string{"OPC", "RQC", "QYE", "DDE", "EXE", "CME", "URQ", "PON"}
+
+// Has reports whether all of the given bits are set.
+func (e StandardEventStatus) Has(bits StandardEventStatus) bool { return e&bits == bits }
+
+// Errors returns the error bits that are set: QYE, DDE, EXE, and CME.
+func (e StandardEventStatus) Errors() StandardEventStatus {
+	return e & (EventQYE | EventDDE | EventEXE | EventCME)
+}
+
+// String returns the names of the bits that are set separated by |, such as
+// "OPC|CME", or "0" if no bits are set.
+func (e StandardEventStatus) String() string {
+	return registerString(uint8(e), eventStatusNames)
+}
+
+// registerString returns the names of the bits set in v, using the bit number
+// for unnamed bits.
+func registerString(v uint8, names [8]string) string {
+	if v == 0 {
+		return "0"
+	}
+	var bits []string
+	for i, name := range names {
+		if v&(1<<i) == 0 {
+			continue
+		}
+		if name == "" {
+			name = "bit" + strconv.Itoa(i)
+		}
+		bits = append(bits, name)
+	}
+	return strings.Join(bits, "|")
+}
+
+// ReadStatusByte reads the status byte register using *STB?.
+func (d *Device) ReadStatusByte(ctx context.Context) (StatusByte, error) {
+	v, err := queryParse(ctx, d, "*STB?", parseRegister)
+	return StatusByte(v), err
+}
+
+// ReadEventStatus reads the standard event status register using *ESR?, which
+// also clears it.
+func (d *Device) ReadEventStatus(ctx context.Context) (StandardEventStatus, error) {
+	v, err := queryParse(ctx, d, "*ESR?", parseRegister)
+	return StandardEventStatus(v), err
+}
+
+// ClearStatus clears the status registers and error queue using *CLS.
+func (d *Device) ClearStatus(ctx context.Context) error {
+	return d.Command(ctx, "*CLS")
+}
+
+// SetEventStatusEnable sets which standard event status bits are summarized by
+// the ESB bit of the status byte using *ESE.
+func (d *Device) SetEventStatusEnable(ctx context.Context, mask StandardEventStatus) error {
+	return d.Command(ctx, "*ESE %d", uint8(mask))
+}
+
+// SetServiceRequestEnable sets which status byte bits request service using
+// *SRE.
+func (d *Device) SetServiceRequestEnable(ctx context.Context, mask StatusByte) error {
+	return d.Command(ctx, "*SRE %d", uint8(mask))
+}
+
+// parseRegister parses an 8-bit register value, such as +32.
+func parseRegister(s string) (uint8, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > 255 {
+		return 0, fmt.Errorf("%w: %d", errInvalidRegister, n)
+	}
+	return uint8(n), nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStatusByteString(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		stb  StatusByte
+		want string
+	}{
+		{stb: 0, want: "0"},
+		{stb: StatusMAV, want: "MAV"},
+		{stb: StatusMAV | StatusESB, want: "MAV|ESB"},
+		{stb: 0xff, want: "bit0|bit1|EAV|QUES|MAV|ESB|RQS|OPER"},
+	}
+	for _, tc := range testCases {
+		if got := tc.stb.String(); got != tc.want {
+			t.Errorf("StatusByte(%d).String() = %q, want %q", uint8(tc.stb), got, tc.want)
+		}
+	}
+}
+
+func TestStandardEventStatus(t *testing.T) {
+	t.Parallel()
+
+	esr := EventOPC | EventCME | EventPON
+	if got, want := esr.String(), "OPC|CME|PON"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if !esr.Has(EventOPC | EventCME) {
+		t.Error("Has(OPC|CME) = false, want true")
+	}
+	if esr.Has(EventOPC | EventEXE) {
+		t.Error("Has(OPC|EXE) = true, want false")
+	}
+	if got := esr.Errors(); got != EventCME {
+		t.Errorf("Errors() = %v, want %v", got, EventCME)
+	}
+}
+
+func TestReadStatusByte(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDevice(newMockPort("+48\n"))
+	got, err := d.ReadStatusByte(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := StatusMAV | StatusESB; got != want {
+		t.Errorf("ReadStatusByte() = %v, want %v", got, want)
+	}
+	if !got.Has(StatusMAV) {
+		t.Error("Has(MAV) = false, want true")
+	}
+
+	d = newTestDevice(newMockPort("256\n"))
+	_, err = d.ReadStatusByte(context.Background())
+	var re *ResponseError
+	if !errors.As(err, &re) || !errors.Is(err, errInvalidRegister) {
+		t.Errorf("err = %v, want *ResponseError wrapping %v", err, errInvalidRegister)
+	}
+}
+
+func TestReadEventStatus(t *testing.T) {
+	t.Parallel()
+
+	mp := newMockPort("33\n")
+	d := newTestDevice(mp)
+	got, err := d.ReadEventStatus(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := EventOPC | EventCME; got != want {
+		t.Errorf("ReadEventStatus() = %v, want %v", got, want)
+	}
+	if got := mp.writeBuf.String(); got != "*ESR?\n" {
+		t.Errorf("written = %q, want %q", got, "*ESR?\n")
+	}
+}
+
+func TestStatusCommands(t *testing.T) {
+	t.Parallel()
+
+	mp := newMockPort("")
+	d := newTestDevice(mp)
+	ctx := context.Background()
+	if err := d.ClearStatus(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.SetEventStatusEnable(ctx, EventOPC|EventEXE|EventCME); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.SetServiceRequestEnable(ctx, StatusESB|StatusMAV); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "*CLS\n*ESE 49\n*SRE 48\n"
+	if got := mp.writeBuf.String(); got != want {
+		t.Errorf("written = %q, want %q", got, want)
+	}
+}
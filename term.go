@@ -0,0 +1,179 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+)
+
+// WriteTermination returns the termination appended to every command by
+// Command and Query.
+func (d *Device) WriteTermination() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.writeTerm
+}
+
+// SetWriteTermination sets the termination appended to every command by
+// Command and Query, such as "\r\n". An empty termination sends commands as
+// is.
+func (d *Device) SetWriteTermination(term string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.writeTerm = term
+}
+
+// ReadTermination returns the termination that ends a response read by Query.
+func (d *Device) ReadTermination() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.readTerm
+}
+
+// SetReadTermination sets the termination that ends a response read by Query,
+// such as "\r\n".
+func (d *Device) SetReadTermination(term string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.readTerm = term
+}
+
+// StripReadTermination returns whether Query removes the read termination from
+// the end of the response.
+func (d *Device) StripReadTermination() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.stripTerm
+}
+
+// SetStripReadTermination sets whether Query removes the read termination from
+// the end of the response.
+func (d *Device) SetStripReadTermination(strip bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stripTerm = strip
+}
+
+// TermCharEnabled returns whether Query reads the response up to the read
+// termination, similar to the VISA VI_ATTR_TERMCHAR_EN attribute.
+func (d *Device) TermCharEnabled() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.termChar
+}
+
+// SetTermCharEnabled sets whether Query reads the response up to the read
+// termination, similar to the VISA VI_ATTR_TERMCHAR_EN attribute. When
+// disabled, or when the read termination is empty, Query instead reads until
+// no more data arrives within the read timeout, so every Query waits at least
+// the read timeout.
+func (d *Device) SetTermCharEnabled(enabled bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.termChar = enabled
+}
+
+// WithWriteTermination sets the termination appended to every command by
+// Command and Query. The default is "\n".
+func WithWriteTermination(term string) DeviceOption {
+	return func(d *Device) {
+		d.writeTerm = term
+	}
+}
+
+// WithReadTermination sets the termination that ends a response read by
+// Query. The default is "\n".
+func WithReadTermination(term string) DeviceOption {
+	return func(d *Device) {
+		d.readTerm = term
+	}
+}
+
+// WithStripReadTermination sets whether Query removes the read termination
+// from the end of the response. The default is false.
+func WithStripReadTermination(strip bool) DeviceOption {
+	return func(d *Device) {
+		d.stripTerm = strip
+	}
+}
+
+// WithTermCharEnabled sets whether Query reads the response up to the read
+// termination. The default is true.
+func WithTermCharEnabled(enabled bool) DeviceOption {
+	return func(d *Device) {
+		d.termChar = enabled
+	}
+}
+
+// readTermination returns the read termination, or an empty string if
+// TermCharEnabled is false.
+func (d *Device) readTermination() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if !d.termChar {
+		return ""
+	}
+	return d.readTerm
+}
+
+// readResponse reads a response according to the termination settings. The
+// caller must hold the lock.
+func (d *Device) readResponse() (string, error) {
+	var (
+		s   string
+		err error
+	)
+	term := d.readTermination()
+	if term != "" {
+		s, err = readUntil(d.reader, term)
+	} else {
+		s, err = readUntilQuiet(d.reader)
+	}
+	if err == nil && term != "" && d.StripReadTermination() {
+		s = strings.TrimSuffix(s, term)
+	}
+	return s, d.timeoutError(err, []byte(s))
+}
+
+// readUntil reads from r until the data read ends with the possibly multi-byte
+// termination term, returning the data including the termination.
+func readUntil(r *bufio.Reader, term string) (string, error) {
+	last := term[len(term)-1]
+	var buf []byte
+	for {
+		chunk, err := r.ReadSlice(last)
+		buf = append(buf, chunk...)
+		switch {
+		case err == nil:
+			if bytes.HasSuffix(buf, []byte(term)) {
+				return string(buf), nil
+			}
+		case errors.Is(err, bufio.ErrBufferFull):
+		default:
+			return string(buf), err
+		}
+	}
+}
+
+// readUntilQuiet reads from r until the read timeout elapses without any more
+// data arriving. It returns ErrTimeout if no data arrives at all.
+func readUntilQuiet(r *bufio.Reader) (string, error) {
+	var buf []byte
+	chunk := make([]byte, 256)
+	for {
+		n, err := r.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		if errors.Is(err, ErrTimeout) && len(buf) > 0 {
+			return string(buf), nil
+		}
+		if err != nil {
+			return string(buf), err
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2017-2026 The asrl developers. All rights reserved.
+// Project site: https://github.com/gotmc/asrl
+// Use of this source code is governed by a MIT-style license that
+// can be found in the LICENSE.txt file for the project.
+
+package asrl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gotmc/asrl/asrltest"
+)
+
+func TestTerminationOptions(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDevice(&mockPort{})
+	if d.WriteTermination() != "\n" || d.ReadTermination() != "\n" {
+		t.Errorf("default terminations = %q, %q, want %q", d.WriteTermination(),
+			d.ReadTermination(), "\n")
+	}
+	if d.StripReadTermination() || !d.TermCharEnabled() {
+		t.Errorf("strip, termchar = %v, %v, want false, true",
+			d.StripReadTermination(), d.TermCharEnabled())
+	}
+
+	d, err := newDevice(&mockPort{}, nil,
+		WithWriteTermination("\r"),
+		WithReadTermination("\r\n"),
+		WithStripReadTermination(true),
+		WithTermCharEnabled(false),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.WriteTermination() != "\r" || d.ReadTermination() != "\r\n" {
+		t.Errorf("terminations = %q, %q, want %q, %q", d.WriteTermination(),
+			d.ReadTermination(), "\r", "\r\n")
+	}
+	if !d.StripReadTermination() || d.TermCharEnabled() {
+		t.Errorf("strip, termchar = %v, %v, want true, false",
+			d.StripReadTermination(), d.TermCharEnabled())
+	}
+	if got := d.EndMark(); got != '\r' {
+		t.Errorf("EndMark() = %q, want %q", got, '\r')
+	}
+}
+
+func TestQueryTerminations(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		inTerm   string
+		outTerm  string
+		response string
+		opts     []DeviceOption
+		want     string
+	}{
+		{
+			name:    "CR in, CR LF out",
+			inTerm:  "\r",
+			outTerm: "\r\n",
+			opts:    []DeviceOption{WithWriteTermination("\r"), WithReadTermination("\r\n")},
+			want:    "ACME,1\r\n",
+		},
+		{
+			name:    "CR LF in, CR out",
+			inTerm:  "\r\n",
+			outTerm: "\r",
+			opts:    []DeviceOption{WithWriteTermination("\r\n"), WithReadTermination("\r")},
+			want:    "ACME,1\r",
+		},
+		{
+			name:     "LF inside CR LF response",
+			inTerm:   "\n",
+			outTerm:  "\r\n",
+			response: "A\nB",
+			opts:     []DeviceOption{WithReadTermination("\r\n")},
+			want:     "A\nB\r\n",
+		},
+		{
+			name:    "strip",
+			inTerm:  "\n",
+			outTerm: "\r\n",
+			opts:    []DeviceOption{WithReadTermination("\r\n"), WithStripReadTermination(true)},
+			want:    "ACME,1",
+		},
+		{
+			name:    "termchar disabled",
+			inTerm:  "\n",
+			outTerm: "\n",
+			opts:    []DeviceOption{WithTermCharEnabled(false)},
+			want:    "ACME,1\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			inst := asrltest.NewInstrument(
+				asrltest.WithInputTerminator(tc.inTerm),
+				asrltest.WithOutputTerminator(tc.outTerm),
+			)
+			response := tc.response
+			if response == "" {
+				response = "ACME,1"
+			}
+			inst.Handle("*IDN?", response)
+			opts := append([]DeviceOption{
+				WithDelayTime(time.Millisecond),
+				WithReadTimeout(50 * time.Millisecond),
+			}, tc.opts...)
+			d, err := NewDeviceFromPort(inst, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := d.Query(context.Background(), "*IDN?")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Query() = %q, want %q", got, tc.want)
+			}
+			if cmds := inst.Commands(); len(cmds) != 1 || cmds[0] != "*IDN?" {
+				t.Errorf("Commands() = %q, want [*IDN?]", cmds)
+			}
+		})
+	}
+}
+
+func TestQueryTermCharDisabledNoResponse(t *testing.T) {
+	t.Parallel()
+
+	inst := asrltest.NewInstrument()
+	d, err := NewDeviceFromPort(inst,
+		WithTermCharEnabled(false),
+		WithDelayTime(time.Millisecond),
+		WithReadTimeout(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = d.Query(context.Background(), "*IDN?")
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want %v", err, ErrTimeout)
+	}
+}
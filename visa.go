@@ -9,7 +9,11 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"runtime"
 	"strconv"
+	"strings"
+	"sync"
+	"unicode"
 
 	"go.bug.st/serial"
 )
@@ -21,20 +25,28 @@ var (
 	ErrInvalidResourceClass = errors.New("visa: resource class was not INSTR")
 	ErrInvalidBaud          = errors.New("visa: invalid baud")
 	ErrUnsupportedDataflow  = errors.New("visa: unsupported dataflow")
+	ErrUnknownBoard         = errors.New("visa: unknown ASRL board number")
 )
 
-var visaResourceRE = regexp.MustCompile(
-	`^(?P<interfaceType>ASRL)(?P<boardIndex>\d*)::` +
-		`(?P<address>[^\s]+)::` +
-		`(?P<baud>\d+)::` +
-		`(?P<dataflow>\d{1}\w{1}\d{1})::` +
-		`(?P<resourceClass>INSTR)$`,
+// Default VISA serial settings used when the resource string omits them.
+const (
+	DefaultBaud     = 9600
+	DefaultDataflow = "8N1"
+)
+
+var (
+	asrlBoardRE     = regexp.MustCompile(`(?i)^ASRL(\d*)$`)
+	asrlAddressRE   = regexp.MustCompile(`(?i)^ASRL(\S+)$`)
+	comPortRE       = regexp.MustCompile(`(?i)^COM(\d+)$`)
+	interfaceTypeRE = regexp.MustCompile(`^[A-Za-z]+\d*$`)
+	digitsRE        = regexp.MustCompile(`^\d+$`)
 )
 
 // VisaResource represents a VISA enabled piece of test equipment.
 type VisaResource struct {
 	resourceString string
 	interfaceType  string
+	boardIndex     int
 	address        string
 	baud           int
 	dataBits       int
@@ -44,38 +56,90 @@ type VisaResource struct {
 }
 
 // NewVisaResource creates a new VisaResource using the given VISA
-// resourceString. If the dataflow isn't provided as part of the VISA resource
-// string, the dataflow will default to 8N1.
+// resourceString. The following forms are accepted, where the optional baud
+// and dataflow fields follow the port in that order:
+//
+//	ASRL[board]::<port>[::<baud>[::<dataflow>]]::INSTR
+//	ASRL<board>[::<baud>[::<dataflow>]]::INSTR
+//	ASRL<port>[::<baud>[::<dataflow>]]::INSTR
+//	COM<n>[::INSTR]
+//
+// For example, ASRL::/dev/ttyUSB0::9600::8N2::INSTR, ASRL1::INSTR,
+// ASRL/dev/ttyUSB0::INSTR, and COM3 are all valid. Board numbers, including
+// COM<n>, are mapped to serial port addresses using the table maintained by
+// RegisterBoard or, failing that, the operating system's naming convention.
+// The INSTR resource class may only be omitted when the resource string has no
+// other fields, such as ASRL1 or COM3. If the baud or dataflow isn't provided,
+// they default to the VISA defaults of 9600 and 8N1. The flow control method
+// isn't part of the resource string; set it with WithFlowControl.
 func NewVisaResource(resourceString string) (*VisaResource, error) {
-	res := visaResourceRE.FindStringSubmatch(resourceString)
-	if res == nil {
-		return nil, ErrInvalidResource
-	}
-	subexpNames := visaResourceRE.SubexpNames()
-	matchMap := map[string]string{}
-	for i, n := range res {
-		matchMap[subexpNames[i]] = n
-	}
-
-	if matchMap["interfaceType"] != "ASRL" {
-		return nil, ErrInvalidInterfaceType
+	fields := strings.Split(resourceString, "::")
+	if len(fields) > 1 {
+		if !strings.EqualFold(fields[len(fields)-1], "INSTR") {
+			return nil, ErrInvalidResource
+		}
+		fields = fields[:len(fields)-1]
 	}
-
-	if matchMap["resourceClass"] != "INSTR" {
-		return nil, ErrInvalidResourceClass
+	for _, f := range fields {
+		if f == "" || strings.ContainsFunc(f, unicode.IsSpace) {
+			return nil, ErrInvalidResource
+		}
 	}
 
 	visa := &VisaResource{
 		resourceString: resourceString,
 		interfaceType:  "ASRL",
 		resourceClass:  "INSTR",
-		address:        matchMap["address"],
+		baud:           DefaultBaud,
 	}
 
-	if matchMap["baud"] != "" {
-		baud, err := strconv.Atoi(matchMap["baud"])
+	head, rest := fields[0], fields[1:]
+	switch {
+	case comPortRE.MatchString(head):
+		if err := visa.setBoard(comPortRE.FindStringSubmatch(head)[1]); err != nil {
+			return nil, err
+		}
+	case asrlBoardRE.MatchString(head):
+		board := asrlBoardRE.FindStringSubmatch(head)[1]
+		if len(rest) > 0 && !digitsRE.MatchString(rest[0]) {
+			// The port is given in its own field, so any board number is
+			// informational only.
+			visa.boardIndex, _ = strconv.Atoi(board)
+			visa.address, rest = rest[0], rest[1:]
+			break
+		}
+		if board == "" {
+			return nil, ErrInvalidResource
+		}
+		if err := visa.setBoard(board); err != nil {
+			return nil, err
+		}
+	case asrlAddressRE.MatchString(head):
+		address := asrlAddressRE.FindStringSubmatch(head)[1]
+		if m := comPortRE.FindStringSubmatch(address); m != nil {
+			if err := visa.setBoard(m[1]); err != nil {
+				return nil, err
+			}
+			break
+		}
+		visa.address = address
+	case len(fields) > 1 && interfaceTypeRE.MatchString(head):
+		return nil, ErrInvalidInterfaceType
+	default:
+		return nil, ErrInvalidResource
+	}
+
+	if len(rest) > 2 {
+		return nil, ErrInvalidResource
+	}
+
+	if len(rest) > 0 {
+		if !digitsRE.MatchString(rest[0]) {
+			return nil, fmt.Errorf("%w %q", ErrInvalidBaud, rest[0])
+		}
+		baud, err := strconv.Atoi(rest[0])
 		if err != nil {
-			return nil, fmt.Errorf("%w %q: %w", ErrInvalidBaud, matchMap["baud"], err)
+			return nil, fmt.Errorf("%w %q: %w", ErrInvalidBaud, rest[0], err)
 		}
 		if baud <= 0 {
 			return nil, fmt.Errorf("%w: %d", ErrInvalidBaud, baud)
@@ -83,40 +147,144 @@ func NewVisaResource(resourceString string) (*VisaResource, error) {
 		visa.baud = baud
 	}
 
-	if matchMap["dataflow"] != "" {
-		switch matchMap["dataflow"] {
-		case "8N1":
-			visa.dataBits = 8
-			visa.parity = serial.NoParity
-			visa.stopBits = serial.OneStopBit
-		case "8N2":
-			visa.dataBits = 8
-			visa.parity = serial.NoParity
-			visa.stopBits = serial.TwoStopBits
-		case "7E2":
-			visa.dataBits = 7
-			visa.parity = serial.EvenParity
-			visa.stopBits = serial.TwoStopBits
-		case "7E1":
-			visa.dataBits = 7
-			visa.parity = serial.EvenParity
-			visa.stopBits = serial.OneStopBit
-		case "7O1":
-			visa.dataBits = 7
-			visa.parity = serial.OddParity
-			visa.stopBits = serial.OneStopBit
-		default:
-			return nil, fmt.Errorf("%w %q", ErrUnsupportedDataflow, matchMap["dataflow"])
-		}
-	} else {
-		visa.dataBits = 8
-		visa.parity = serial.NoParity
-		visa.stopBits = serial.OneStopBit
+	dataflow := DefaultDataflow
+	if len(rest) > 1 {
+		dataflow = rest[1]
+	}
+	dataBits, parity, stopBits, err := parseDataflow(dataflow)
+	if err != nil {
+		return nil, err
 	}
+	visa.dataBits = dataBits
+	visa.parity = parity
+	visa.stopBits = stopBits
 
 	return visa, nil
 }
 
+// setBoard sets the board index and maps it to a serial port address.
+func (v *VisaResource) setBoard(board string) error {
+	n, err := strconv.Atoi(board)
+	if err != nil {
+		return fmt.Errorf("%w %q: %w", ErrUnknownBoard, board, err)
+	}
+	address, err := boardAddress(n)
+	if err != nil {
+		return err
+	}
+	v.boardIndex = n
+	v.address = address
+	return nil
+}
+
+var (
+	boardMu        sync.RWMutex
+	boardAddresses = map[int]string{}
+)
+
+// RegisterBoard maps a VISA ASRL board number to a serial port address, so
+// that ASRL<board>::INSTR and COM<board> open the given port. This is useful
+// for USB serial adapters, such as mapping board 1 to /dev/ttyUSB0 on Linux.
+// An empty address removes the mapping.
+func RegisterBoard(board int, address string) {
+	boardMu.Lock()
+	defer boardMu.Unlock()
+	if address == "" {
+		delete(boardAddresses, board)
+		return
+	}
+	boardAddresses[board] = address
+}
+
+// boardAddress returns the serial port address for a board number, using the
+// RegisterBoard table before falling back to the operating system default.
+func boardAddress(board int) (string, error) {
+	boardMu.RLock()
+	address, ok := boardAddresses[board]
+	boardMu.RUnlock()
+	if ok {
+		return address, nil
+	}
+	return defaultBoardAddress(runtime.GOOS, board)
+}
+
+// defaultBoardAddress maps a board number to the operating system's name for
+// the serial port: COM<n> on Windows and /dev/ttyS<n-1> on Linux, since VISA
+// board numbers start at 1. Other operating systems have no fixed naming
+// convention, so their boards must be registered using RegisterBoard.
+func defaultBoardAddress(goos string, board int) (string, error) {
+	if board < 1 {
+		return "", fmt.Errorf("%w: %d", ErrUnknownBoard, board)
+	}
+	switch goos {
+	case "windows":
+		return fmt.Sprintf("COM%d", board), nil
+	case "linux":
+		return fmt.Sprintf("/dev/ttyS%d", board-1), nil
+	default:
+		return "", fmt.Errorf("%w %d on %s: use RegisterBoard", ErrUnknownBoard, board, goos)
+	}
+}
+
+var dataflowParities = map[string]serial.Parity{
+	"N": serial.NoParity,
+	"E": serial.EvenParity,
+	"O": serial.OddParity,
+	"M": serial.MarkParity,
+	"S": serial.SpaceParity,
+}
+
+var dataflowStopBits = map[string]serial.StopBits{
+	"1":   serial.OneStopBit,
+	"1.5": serial.OnePointFiveStopBits,
+	"2":   serial.TwoStopBits,
+}
+
+// parseDataflow parses a dataflow string made up of the number of data bits
+// (5 to 8), the parity (N for none, E for even, O for odd, M for mark, or S for
+// space), and the number of stop bits (1, 1.5, or 2), such as "8N1" or "5E1.5".
+// As with a standard UART, 1.5 stop bits requires 5 data bits, and 2 stop bits
+// cannot be used with 5 data bits.
+func parseDataflow(dataflow string) (int, serial.Parity, serial.StopBits, error) {
+	fail := func(reason string) (int, serial.Parity, serial.StopBits, error) {
+		return 0, 0, 0, fmt.Errorf("%w %q: %s", ErrUnsupportedDataflow, dataflow, reason)
+	}
+	if len(dataflow) < 3 {
+		return fail("too short")
+	}
+
+	dataBits := int(dataflow[0] - '0')
+	if dataBits < 5 || dataBits > 8 {
+		return fail("data bits must be 5, 6, 7, or 8")
+	}
+	parity, ok := dataflowParities[strings.ToUpper(dataflow[1:2])]
+	if !ok {
+		return fail("parity must be N, E, O, M, or S")
+	}
+	stopBits, ok := dataflowStopBits[dataflow[2:]]
+	if !ok {
+		return fail("stop bits must be 1, 1.5, or 2")
+	}
+
+	switch {
+	case stopBits == serial.OnePointFiveStopBits && dataBits != 5:
+		return fail("1.5 stop bits requires 5 data bits")
+	case stopBits == serial.TwoStopBits && dataBits == 5:
+		return fail("2 stop bits cannot be used with 5 data bits")
+	}
+	return dataBits, parity, stopBits, nil
+}
+
+// mode returns the serial port settings given in the resource string.
+func (v *VisaResource) mode() *serial.Mode {
+	return &serial.Mode{
+		BaudRate: v.baud,
+		DataBits: v.dataBits,
+		Parity:   v.parity,
+		StopBits: v.stopBits,
+	}
+}
+
 // String returns the original VISA resource string.
 func (v *VisaResource) String() string {
 	return v.resourceString
@@ -127,6 +295,12 @@ func (v *VisaResource) InterfaceType() string {
 	return v.interfaceType
 }
 
+// BoardIndex returns the VISA board number, or 0 if the resource string didn't
+// include one.
+func (v *VisaResource) BoardIndex() int {
+	return v.boardIndex
+}
+
 // Address returns the serial port address.
 func (v *VisaResource) Address() string {
 	return v.address
@@ -7,6 +7,8 @@ package asrl
 
 import (
 	"errors"
+	"fmt"
+	"runtime"
 	"testing"
 
 	"go.bug.st/serial"
@@ -14,6 +16,7 @@ import (
 
 func TestParsingVisaResourceString(t *testing.T) {
 	t.Parallel()
+	RegisterBoard(17, "/dev/ttyUSB3")
 
 	testCases := []struct {
 		name           string
@@ -24,6 +27,7 @@ func TestParsingVisaResourceString(t *testing.T) {
 		dataBits       int
 		parity         serial.Parity
 		stopBits       serial.StopBits
+		boardIndex     int
 		resourceClass  string
 		wantErr        error
 	}{
@@ -82,6 +86,163 @@ func TestParsingVisaResourceString(t *testing.T) {
 			stopBits:       serial.OneStopBit,
 			resourceClass:  "INSTR",
 		},
+		{
+			name:           "5 data bits mark parity 1.5 stop bits",
+			resourceString: "ASRL::/dev/ttyUSB0::300::5M1.5::INSTR",
+			interfaceType:  "ASRL",
+			address:        "/dev/ttyUSB0",
+			baud:           300,
+			dataBits:       5,
+			parity:         serial.MarkParity,
+			stopBits:       serial.OnePointFiveStopBits,
+			resourceClass:  "INSTR",
+		},
+		{
+			name:           "6 data bits space parity",
+			resourceString: "ASRL::/dev/ttyUSB0::1200::6S2::INSTR",
+			interfaceType:  "ASRL",
+			address:        "/dev/ttyUSB0",
+			baud:           1200,
+			dataBits:       6,
+			parity:         serial.SpaceParity,
+			stopBits:       serial.TwoStopBits,
+			resourceClass:  "INSTR",
+		},
+		{
+			name:           "illegal dataflow combination",
+			resourceString: "ASRL::/dev/ttyUSB0::9600::8N1.5::INSTR",
+			wantErr:        ErrUnsupportedDataflow,
+		},
+		{
+			name:           "board number only",
+			resourceString: "ASRL17::INSTR",
+			interfaceType:  "ASRL",
+			address:        "/dev/ttyUSB3",
+			baud:           9600,
+			dataBits:       8,
+			parity:         serial.NoParity,
+			stopBits:       serial.OneStopBit,
+			boardIndex:     17,
+			resourceClass:  "INSTR",
+		},
+		{
+			name:           "board number with baud and dataflow",
+			resourceString: "asrl17::19200::7E1::instr",
+			interfaceType:  "ASRL",
+			address:        "/dev/ttyUSB3",
+			baud:           19200,
+			dataBits:       7,
+			parity:         serial.EvenParity,
+			stopBits:       serial.OneStopBit,
+			boardIndex:     17,
+			resourceClass:  "INSTR",
+		},
+		{
+			name:           "board number without resource class",
+			resourceString: "ASRL17",
+			interfaceType:  "ASRL",
+			address:        "/dev/ttyUSB3",
+			baud:           9600,
+			dataBits:       8,
+			parity:         serial.NoParity,
+			stopBits:       serial.OneStopBit,
+			boardIndex:     17,
+			resourceClass:  "INSTR",
+		},
+		{
+			name:           "board number with port",
+			resourceString: "ASRL2::/dev/ttyUSB0::9600::8N1::INSTR",
+			interfaceType:  "ASRL",
+			address:        "/dev/ttyUSB0",
+			baud:           9600,
+			dataBits:       8,
+			parity:         serial.NoParity,
+			stopBits:       serial.OneStopBit,
+			boardIndex:     2,
+			resourceClass:  "INSTR",
+		},
+		{
+			name:           "port appended to interface type",
+			resourceString: "ASRL/dev/ttyUSB0::INSTR",
+			interfaceType:  "ASRL",
+			address:        "/dev/ttyUSB0",
+			baud:           9600,
+			dataBits:       8,
+			parity:         serial.NoParity,
+			stopBits:       serial.OneStopBit,
+			resourceClass:  "INSTR",
+		},
+		{
+			name:           "port appended to interface type with baud",
+			resourceString: "ASRL/dev/ttyUSB0::115200::INSTR",
+			interfaceType:  "ASRL",
+			address:        "/dev/ttyUSB0",
+			baud:           115200,
+			dataBits:       8,
+			parity:         serial.NoParity,
+			stopBits:       serial.OneStopBit,
+			resourceClass:  "INSTR",
+		},
+		{
+			name:           "port with default baud and dataflow",
+			resourceString: "ASRL::/dev/ttyUSB0::INSTR",
+			interfaceType:  "ASRL",
+			address:        "/dev/ttyUSB0",
+			baud:           9600,
+			dataBits:       8,
+			parity:         serial.NoParity,
+			stopBits:       serial.OneStopBit,
+			resourceClass:  "INSTR",
+		},
+		{
+			name:           "COM port",
+			resourceString: "COM17",
+			interfaceType:  "ASRL",
+			address:        "/dev/ttyUSB3",
+			baud:           9600,
+			dataBits:       8,
+			parity:         serial.NoParity,
+			stopBits:       serial.OneStopBit,
+			boardIndex:     17,
+			resourceClass:  "INSTR",
+		},
+		{
+			name:           "COM port appended to interface type",
+			resourceString: "ASRLCOM17::INSTR",
+			interfaceType:  "ASRL",
+			address:        "/dev/ttyUSB3",
+			baud:           9600,
+			dataBits:       8,
+			parity:         serial.NoParity,
+			stopBits:       serial.OneStopBit,
+			boardIndex:     17,
+			resourceClass:  "INSTR",
+		},
+		{
+			name:           "interface type without board or port",
+			resourceString: "ASRL::INSTR",
+			wantErr:        ErrInvalidResource,
+		},
+		{
+			name:           "wrong interface type",
+			resourceString: "GPIB0::5::INSTR",
+			wantErr:        ErrInvalidInterfaceType,
+		},
+		{
+			name:           "invalid baud",
+			resourceString: "ASRL/dev/ttyUSB0::fast::INSTR",
+			wantErr:        ErrInvalidBaud,
+		},
+		{
+			name:           "too many fields",
+			resourceString: "ASRL::/dev/ttyUSB0::9600::8N1::extra::INSTR",
+			wantErr:        ErrInvalidResource,
+		},
+		{
+			name:           "empty field",
+			resourceString: "ASRL1::::INSTR",
+			wantErr:        ErrInvalidResource,
+		},
 		{
 			name:           "completely invalid string",
 			resourceString: "not-a-visa-string",
@@ -137,9 +298,127 @@ func TestParsingVisaResourceString(t *testing.T) {
 			if resource.stopBits != tc.stopBits {
 				t.Errorf("stopBits = %d, want %d", resource.stopBits, tc.stopBits)
 			}
+			if resource.boardIndex != tc.boardIndex {
+				t.Errorf("boardIndex = %d, want %d", resource.boardIndex, tc.boardIndex)
+			}
 			if resource.resourceClass != tc.resourceClass {
 				t.Errorf("resourceClass = %s, want %s", resource.resourceClass, tc.resourceClass)
 			}
 		})
 	}
 }
+
+func TestParseDataflow(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		dataflow string
+		dataBits int
+		parity   serial.Parity
+		stopBits serial.StopBits
+		wantErr  bool
+	}{
+		{dataflow: "8N1", dataBits: 8, parity: serial.NoParity, stopBits: serial.OneStopBit},
+		{dataflow: "8N2", dataBits: 8, parity: serial.NoParity, stopBits: serial.TwoStopBits},
+		{dataflow: "7E1", dataBits: 7, parity: serial.EvenParity, stopBits: serial.OneStopBit},
+		{dataflow: "7e2", dataBits: 7, parity: serial.EvenParity, stopBits: serial.TwoStopBits},
+		{dataflow: "7O1", dataBits: 7, parity: serial.OddParity, stopBits: serial.OneStopBit},
+		{dataflow: "6M1", dataBits: 6, parity: serial.MarkParity, stopBits: serial.OneStopBit},
+		{dataflow: "6S2", dataBits: 6, parity: serial.SpaceParity, stopBits: serial.TwoStopBits},
+		{dataflow: "5N1", dataBits: 5, parity: serial.NoParity, stopBits: serial.OneStopBit},
+		{
+			dataflow: "5O1.5",
+			dataBits: 5,
+			parity:   serial.OddParity,
+			stopBits: serial.OnePointFiveStopBits,
+		},
+		{dataflow: "4N1", wantErr: true},
+		{dataflow: "9N1", wantErr: true},
+		{dataflow: "8X1", wantErr: true},
+		{dataflow: "8N0", wantErr: true},
+		{dataflow: "8N3", wantErr: true},
+		{dataflow: "8N1.5", wantErr: true},
+		{dataflow: "7E1.5", wantErr: true},
+		{dataflow: "5N2", wantErr: true},
+		{dataflow: "8N", wantErr: true},
+		{dataflow: "", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.dataflow, func(t *testing.T) {
+			t.Parallel()
+			dataBits, parity, stopBits, err := parseDataflow(tc.dataflow)
+			if tc.wantErr {
+				if !errors.Is(err, ErrUnsupportedDataflow) {
+					t.Fatalf("err = %v, want %v", err, ErrUnsupportedDataflow)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if dataBits != tc.dataBits {
+				t.Errorf("dataBits = %d, want %d", dataBits, tc.dataBits)
+			}
+			if parity != tc.parity {
+				t.Errorf("parity = %d, want %d", parity, tc.parity)
+			}
+			if stopBits != tc.stopBits {
+				t.Errorf("stopBits = %d, want %d", stopBits, tc.stopBits)
+			}
+		})
+	}
+}
+
+func TestDefaultBoardAddress(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		goos    string
+		board   int
+		want    string
+		wantErr bool
+	}{
+		{goos: "windows", board: 1, want: "COM1"},
+		{goos: "windows", board: 12, want: "COM12"},
+		{goos: "linux", board: 1, want: "/dev/ttyS0"},
+		{goos: "linux", board: 4, want: "/dev/ttyS3"},
+		{goos: "linux", board: 0, wantErr: true},
+		{goos: "darwin", board: 1, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s board %d", tc.goos, tc.board), func(t *testing.T) {
+			t.Parallel()
+			got, err := defaultBoardAddress(tc.goos, tc.board)
+			if tc.wantErr {
+				if !errors.Is(err, ErrUnknownBoard) {
+					t.Fatalf("err = %v, want %v", err, ErrUnknownBoard)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("address = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRegisterBoard(t *testing.T) {
+	t.Parallel()
+	RegisterBoard(99, "/dev/ttyUSB9")
+	got, err := boardAddress(99)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "/dev/ttyUSB9" {
+		t.Errorf("address = %q, want %q", got, "/dev/ttyUSB9")
+	}
+	RegisterBoard(99, "")
+	got, err = boardAddress(99)
+	want, wantErr := defaultBoardAddress(runtime.GOOS, 99)
+	if got != want || (err == nil) != (wantErr == nil) {
+		t.Errorf("boardAddress = %q, %v, want %q, %v", got, err, want, wantErr)
+	}
+}